@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 
 	"github.com/yuya-isaka/go-yuya-monkey/token"
@@ -21,6 +22,13 @@ type Expression interface {
 	expression()
 }
 
+// 値の形を表すもの（letの分割代入とmatchで使う）
+// 変数（_なら何でも）、リテラル、[a, b, ...rest]、{name, "key": p}
+type Pattern interface {
+	Node
+	pattern()
+}
+
 // ---------------------------------
 
 type ProgramNode struct {
@@ -40,9 +48,10 @@ func (p ProgramNode) String() string {
 //--------------------
 
 type LetNode struct {
-	Token token.Token // 先頭のトークン
-	Name  *IdentNode  // 変数名
-	Value Expression  // 中の式
+	Token   token.Token // 先頭のトークン
+	Name    *IdentNode  // 変数名
+	Pattern Pattern     // 分割代入のときはこっち（Nameはnil）
+	Value   Expression  // 中の式
 }
 
 func (l LetNode) statement() {}
@@ -50,7 +59,11 @@ func (l LetNode) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(l.Token.Name + " ")
-	out.WriteString(l.Name.String())
+	if l.Pattern != nil {
+		out.WriteString(l.Pattern.String())
+	} else {
+		out.WriteString(l.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if l.Value != nil {
@@ -113,6 +126,213 @@ func (b BlockNode) String() string {
 	return out.String()
 }
 
+// fn 名前(引数) { ... }
+// 同じブロックの中なら、書いた場所より前からでも呼べる（巻き上げ）
+type FuncDeclNode struct {
+	Token    token.Token   // 'fn'トークン
+	Name     *IdentNode    // 関数名
+	Function *FunctionNode // 中身は関数式と同じ
+}
+
+func (f FuncDeclNode) statement() {}
+func (f FuncDeclNode) String() string {
+	var out bytes.Buffer
+
+	fn := f.Function
+
+	out.WriteString(f.Token.Name + " ")
+	out.WriteString(f.Name.String())
+	out.WriteString("(")
+	out.WriteString(FormatParameters(fn.Parameters, fn.Defaults, fn.Rest))
+	out.WriteString(") ")
+	out.WriteString(f.Function.Body.String())
+
+	return out.String()
+}
+
+// 再代入（x = 1, x += 1）
+// letと違って、すでにある変数を書き換える
+type AssignNode struct {
+	Token    token.Token // "="や"+="のトークン
+	Name     *IdentNode  // 変数名
+	Operator string      // "=", "+=", "-=", "*=", "/="
+	Value    Expression  // 右辺
+}
+
+func (a AssignNode) statement() {}
+func (a AssignNode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(a.Name.String())
+	out.WriteString(" " + a.Operator + " ")
+
+	if a.Value != nil {
+		out.WriteString(a.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// while (条件) { ... }
+type WhileNode struct {
+	Token     token.Token // 'while'トークン
+	Condition Expression  // 条件式
+	Body      *BlockNode  // ブロックノード
+}
+
+func (w WhileNode) statement() {}
+func (w WhileNode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(w.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(w.Body.String())
+
+	return out.String()
+}
+
+// for (変数 in 配列か文字列かハッシュ) { ... }
+type ForNode struct {
+	Token    token.Token // 'for'トークン
+	Variable *IdentNode  // ループ変数
+	Iterable Expression  // 回すもの
+	Body     *BlockNode  // ブロックノード
+}
+
+func (f ForNode) statement() {}
+func (f ForNode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	out.WriteString(f.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(f.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(f.Body.String())
+
+	return out.String()
+}
+
+type BreakNode struct {
+	Token token.Token // 'break'トークン
+}
+
+func (b BreakNode) statement()     {}
+func (b BreakNode) String() string { return b.Token.Name + ";" }
+
+type ContinueNode struct {
+	Token token.Token // 'continue'トークン
+}
+
+func (c ContinueNode) statement()     {}
+func (c ContinueNode) String() string { return c.Token.Name + ";" }
+
+// ---------------------------------
+// パターン
+
+// [a, b, ...rest]
+type ArrayPatternNode struct {
+	Token    token.Token // '['トークン
+	Elements []Pattern   // 前から順に照らし合わせる
+	Rest     *IdentNode  // 残りを配列で受け取る（なければnil）
+}
+
+func (a ArrayPatternNode) pattern() {}
+func (a ArrayPatternNode) String() string {
+	elems := make([]string, 0, len(a.Elements)+1)
+	for _, e := range a.Elements {
+		elems = append(elems, e.String())
+	}
+	if a.Rest != nil {
+		elems = append(elems, "..."+a.Rest.String())
+	}
+
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// {name, age} か {"key": パターン}
+// {name} は {"name": name} の省略
+type HashPatternNode struct {
+	Token  token.Token   // '{'トークン
+	Keys   []*StringNode // キー
+	Values []Pattern     // キーの値と照らし合わせる（Keysと同じ長さ）
+}
+
+func (h HashPatternNode) pattern() {}
+func (h HashPatternNode) String() string {
+	pairs := make([]string, len(h.Keys))
+	for i, k := range h.Keys {
+		if ident, ok := h.Values[i].(*IdentNode); ok && ident.Value == k.Value {
+			pairs[i] = k.Value
+		} else {
+			pairs[i] = strconv.Quote(k.Value) + ": " + h.Values[i].String()
+		}
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// 1, -2.5, "str", true（==で比べる）
+type LiteralPatternNode struct {
+	Token token.Token // 先頭のトークン
+	Value Expression  // リテラル（負の数ならPrefixNode）
+}
+
+func (l LiteralPatternNode) pattern()       {}
+func (l LiteralPatternNode) String() string { return l.Value.String() }
+
+// match (値) { パターン => 式, パターン if 条件 => 式 }
+type MatchNode struct {
+	Token   token.Token // 'match'トークン
+	Subject Expression  // 照らし合わせる値
+	Arms    []*MatchArm // 上から順に試す
+}
+
+type MatchArm struct {
+	Pattern Pattern    // パターン
+	Guard   Expression // ifの条件（なければnil）
+	Body    Expression // 合ったときの値
+}
+
+func (m MatchNode) expression() {}
+func (m MatchNode) String() string {
+	var out bytes.Buffer
+
+	arms := make([]string, len(m.Arms))
+	for i, arm := range m.Arms {
+		s := arm.Pattern.String()
+		if arm.Guard != nil {
+			s += " if " + arm.Guard.String()
+		}
+		arms[i] = s + " => " + arm.Body.String()
+	}
+
+	out.WriteString("match(")
+	out.WriteString(m.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// ---------------------------------
+
+// 構文エラーで読めなかったところ（文でも式でも入る）
+// エラーがあってもASTの残りを使えるように（エディタとか）
+type ErrorNode struct {
+	Token token.Token // 読めなかったところの先頭のトークン
+}
+
+func (e ErrorNode) statement()  {}
+func (e ErrorNode) expression() {}
+func (e ErrorNode) String() string {
+	return "<error>"
+}
+
 // ---------------------------------
 
 // 変数名
@@ -122,6 +342,7 @@ type IdentNode struct {
 }
 
 func (i IdentNode) expression() {}
+func (i IdentNode) pattern()    {} // パターンの中では変数に受け取る（_なら捨てる）
 func (i IdentNode) String() string {
 	return i.Value
 }
@@ -136,6 +357,16 @@ func (i IntNode) String() string {
 	return i.Token.Name
 }
 
+type FloatNode struct {
+	Token token.Token // 先頭のトークン
+	Value float64     // 持つ値
+}
+
+func (f FloatNode) expression() {}
+func (f FloatNode) String() string {
+	return f.Token.Name
+}
+
 type PrefixNode struct {
 	Token    token.Token // 先頭のトークン
 	Operator string      // オペレータ
@@ -208,9 +439,24 @@ func (i IfNode) String() string {
 	return out.String()
 }
 
+// 条件 ? 式 : 式
+type TernaryNode struct {
+	Token       token.Token // '?'トークン
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (t TernaryNode) expression() {}
+func (t TernaryNode) String() string {
+	return "(" + t.Condition.String() + " ? " + t.Consequence.String() + " : " + t.Alternative.String() + ")"
+}
+
 type FunctionNode struct {
 	Token      token.Token  // 'fn'トークン、先頭のトークン
 	Parameters []*IdentNode // 変数の配列
+	Defaults   []Expression // デフォルト値（Parametersと同じ長さ、なければnil）
+	Rest       *IdentNode   // ...rest（なければnil）
 	Body       *BlockNode   // ブロックノード
 }
 
@@ -218,24 +464,39 @@ func (f FunctionNode) expression() {}
 func (f FunctionNode) String() string {
 	var out bytes.Buffer
 
-	params := make([]string, len(f.Parameters))
-	for i, p := range f.Parameters {
-		params[i] = p.String()
-	}
-
 	out.WriteString(f.Token.Name)
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(FormatParameters(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") ")
 	out.WriteString(f.Body.String())
 
 	return out.String()
 }
 
+// 引数の並び（a, b = 2, ...rest）
+// 関数式、関数宣言、関数オブジェクトの表示で使う
+func FormatParameters(params []*IdentNode, defaults []Expression, rest *IdentNode) string {
+	list := make([]string, 0, len(params)+1)
+	for i, p := range params {
+		if i < len(defaults) && defaults[i] != nil {
+			list = append(list, p.String()+" = "+defaults[i].String())
+		} else {
+			list = append(list, p.String())
+		}
+	}
+
+	if rest != nil {
+		list = append(list, "..."+rest.String())
+	}
+
+	return strings.Join(list, ", ")
+}
+
 type CallNode struct {
 	Token     token.Token  // 先頭のトークン
 	Function  Expression   // Identifier or Function
 	Arguments []Expression // 式の配列（先頭から評価）
+	Grouped   bool         // ()で囲まれていた（|> で引数を足さない）
 }
 
 func (c CallNode) expression() {}
@@ -263,6 +524,32 @@ type StringNode struct {
 func (s StringNode) expression()    {}
 func (s StringNode) String() string { return s.Token.Name }
 
+// "hello ${name}, you are ${age}"
+// 文字列と式が交互に並ぶ（Stringsは必ずExprsより1つ多い、空文字列もある）
+type InterpNode struct {
+	Token   token.Token  // INTERP_STARTトークン
+	Strings []string     // 式の間の文字列
+	Exprs   []Expression // ${ }の中の式
+}
+
+func (i InterpNode) expression() {}
+func (i InterpNode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for n, s := range i.Strings {
+		out.WriteString(s)
+		if n < len(i.Exprs) {
+			out.WriteString("${")
+			out.WriteString(i.Exprs[n].String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
 // ---------------------------------
 
 type ArrayNode struct {
@@ -306,6 +593,46 @@ func (i IndexNode) String() string {
 	return out.String()
 }
 
+// h.key（h["key"]と同じ）
+type DotNode struct {
+	Token token.Token // '.'トークン
+	Left  Expression
+	Key   *IdentNode
+}
+
+func (d DotNode) expression() {}
+func (d DotNode) String() string {
+	return "(" + d.Left.String() + "." + d.Key.String() + ")"
+}
+
+// x[start:end]（start, endは省略できる、省略したらnil）
+type SliceNode struct {
+	Token token.Token // '['トークン
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (s SliceNode) expression() {}
+func (s SliceNode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(s.Left.String())
+	out.WriteString("[")
+	if s.Start != nil {
+		out.WriteString(s.Start.String())
+	}
+	out.WriteString(":")
+	if s.End != nil {
+		out.WriteString(s.End.String())
+	}
+	out.WriteString("]")
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // ---------------------------------
 
 type HashNode struct {
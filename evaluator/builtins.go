@@ -2,6 +2,7 @@ package evaluator
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/yuya-isaka/go-yuya-monkey/object"
 )
@@ -18,9 +19,9 @@ var builtins = map[string]*object.BuiltinObj{
 			}
 
 			switch arg := args[0].(type) {
-			// 1. 文字列
+			// 1. 文字列（バイト数じゃなくて文字数）
 			case *object.StringObj:
-				return &object.IntObj{Value: int64(len(arg.Value))}
+				return &object.IntObj{Value: int64(utf8.RuneCountInString(arg.Value))}
 			// 2. 配列
 			case *object.ArrayObj:
 				return &object.IntObj{Value: int64(len(arg.Values))}
@@ -29,11 +30,32 @@ var builtins = map[string]*object.BuiltinObj{
 			}
 		},
 	},
+	// 文字列のバイト数が欲しいとき用
+	"bytelen": &object.BuiltinObj{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorObj("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != object.STRING {
+				return newErrorObj("argument to `bytelen` must be STRING, got %s", args[0].Type())
+			}
+
+			return &object.IntObj{Value: int64(len(args[0].(*object.StringObj).Value))}
+		},
+	},
 	"first": &object.BuiltinObj{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newErrorObj("wrong number of arguments. got=%d, want=1", len(args))
 			}
+			// 文字列なら最初の文字
+			if str, ok := args[0].(*object.StringObj); ok {
+				chars := []rune(str.Value)
+				if len(chars) > 0 {
+					return &object.StringObj{Value: string(chars[0])}
+				}
+				return NULL
+			}
 			if args[0].Type() != object.ARRAY {
 				return newErrorObj("argument to `first` must be ARRAY, got %s", args[0].Type())
 			}
@@ -51,6 +73,14 @@ var builtins = map[string]*object.BuiltinObj{
 			if len(args) != 1 {
 				return newErrorObj("wrong number of arguments. got=%d, want=1", len(args))
 			}
+			// 文字列なら最後の文字
+			if str, ok := args[0].(*object.StringObj); ok {
+				chars := []rune(str.Value)
+				if len(chars) > 0 {
+					return &object.StringObj{Value: string(chars[len(chars)-1])}
+				}
+				return NULL
+			}
 			if args[0].Type() != object.ARRAY {
 				return newErrorObj("argument to `last` must be ARRAY, got %s", args[0].Type())
 			}
@@ -69,6 +99,14 @@ var builtins = map[string]*object.BuiltinObj{
 			if len(args) != 1 {
 				return newErrorObj("wrong number of arguments. got=%d, want=1", len(args))
 			}
+			// 文字列なら最初の文字以外
+			if str, ok := args[0].(*object.StringObj); ok {
+				chars := []rune(str.Value)
+				if len(chars) > 0 {
+					return &object.StringObj{Value: string(chars[1:])}
+				}
+				return NULL
+			}
 			if args[0].Type() != object.ARRAY {
 				return newErrorObj("argument to `rest` must be ARRAY, got %s", args[0].Type())
 			}
@@ -6,9 +6,12 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/yuya-isaka/go-yuya-monkey/ast"
 	"github.com/yuya-isaka/go-yuya-monkey/object"
+	"github.com/yuya-isaka/go-yuya-monkey/token"
 )
 
 // 勿体無いから事前に生成して、参照する
@@ -29,6 +32,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ProgramNode:
 		var obj object.Object
 
+		// 関数宣言は先に登録しておく（後ろで宣言した関数も呼べるように）
+		declareFunctions(node.Statements, env)
+
 		for _, statement := range node.Statements {
 			obj = Eval(statement, env)
 
@@ -40,6 +46,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			// エラーならそのまま
 			case *object.ErrorObj:
 				return obj
+			// ループの外まで来てしまった
+			case *object.BreakObj, *object.ContinueObj:
+				return loopControlError(obj)
 			}
 		}
 
@@ -50,14 +59,17 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.BlockNode:
 		var obj object.Object
 
+		declareFunctions(node.Statements, env)
+
 		for _, statement := range node.Statements {
 			obj = Eval(statement, env)
 
 			if obj != nil {
 				vt := obj.Type()
-				if vt == object.RETURN || vt == object.ERROR {
+				if vt == object.RETURN || vt == object.ERROR || vt == object.BREAK || vt == object.CONTINUE {
 					// そのまま上に上げる
 					// ProgramNodeのところで取り出すため (ProgramNodeのところで終われない)
+					// break, continueはループのところで止まる
 					return obj
 				}
 			}
@@ -69,16 +81,27 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.LetNode:
 		obj := Eval(node.Value, env)
-		if isErrorObj(obj) {
+		if isStopObj(obj) {
 			return obj
 		}
 
+		// ばらして登録（値がない式はNULLとしてばらす）
+		if node.Pattern != nil {
+			if obj == nil {
+				obj = NULL
+			}
+			if errObj := bindPattern(node.Pattern, obj, env); errObj != nil {
+				return errObj
+			}
+			return nil
+		}
+
 		// 環境に登録
 		env.Set(node.Name.Value, obj)
 
 	case *ast.ReturnNode:
 		obj := Eval(node.Value, env)
-		if isErrorObj(obj) {
+		if isStopObj(obj) {
 			return obj
 		}
 		return &object.ReturnObj{Value: obj}
@@ -86,6 +109,25 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.EsNode:
 		return Eval(node.Value, env)
 
+	case *ast.AssignNode:
+		return evalAssign(node, env)
+
+	// ProgramNodeかBlockNodeの最初に登録済み
+	case *ast.FuncDeclNode:
+		return nil
+
+	case *ast.WhileNode:
+		return evalWhile(node, env)
+
+	case *ast.ForNode:
+		return evalFor(node, env)
+
+	case *ast.BreakNode:
+		return &object.BreakObj{Pos: node.Token.Pos}
+
+	case *ast.ContinueNode:
+		return &object.ContinueObj{Pos: node.Token.Pos}
+
 	case *ast.IdentNode:
 		if obj, ok := env.Get(node.Value); ok {
 			return obj
@@ -95,103 +137,87 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return builtin
 		}
 
-		return newErrorObj("identifier not found: " + node.Value)
+		return withPos(newErrorObj("identifier not found: "+node.Value), node.Token.Pos)
+
+	// 構文エラーのところ（パーサーのエラーがあっても評価したとき）
+	case *ast.ErrorNode:
+		return withPos(newErrorObj("invalid syntax"), node.Token.Pos)
 
 	case *ast.IntNode:
 		return &object.IntObj{Value: node.Value}
 
+	case *ast.FloatNode:
+		return &object.FloatObj{Value: node.Value}
+
 	case *ast.BoolNode:
 		return changeBoolObj(node.Value)
 
 	case *ast.StringNode:
 		return &object.StringObj{Value: node.Value}
 
+	// 式の値はInspectで文字列にする（文字列ならそのまま）
+	case *ast.InterpNode:
+		var out strings.Builder
+		for i, str := range node.Strings {
+			out.WriteString(str)
+			if i == len(node.Exprs) {
+				break
+			}
+
+			obj := Eval(node.Exprs[i], env)
+			if isStopObj(obj) {
+				return obj
+			}
+			// 空のブロックなど、値がない式
+			if obj == nil {
+				obj = NULL
+			}
+			out.WriteString(obj.Inspect())
+		}
+		return &object.StringObj{Value: out.String()}
+
 	case *ast.PrefixNode:
 		right := Eval(node.Right, env)
-		if isErrorObj(right) {
+		if isStopObj(right) {
 			return right
 		}
-		return evalPrefix(node.Operator, right)
+		return withPos(evalPrefix(node.Operator, right), node.Token.Pos)
 
 	case *ast.InfixNode:
 		left := Eval(node.Left, env)
-		if isErrorObj(left) {
+		if isStopObj(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
-		if isErrorObj(right) {
-			return right
-		}
-
-		switch {
-
-		// 1.
-		// 先にInt型をやることで、「先に==や!=で変換して比較される」ことを防いでいる
-		case left.Type() == object.INT && right.Type() == object.INT:
-
-			// 値をオブジェクトからアンラップ
-			leftVal := left.(*object.IntObj).Value
-			rightVal := right.(*object.IntObj).Value
-
-			switch node.Operator {
-			case "+":
-				return &object.IntObj{Value: leftVal + rightVal}
-			case "-":
-				return &object.IntObj{Value: leftVal - rightVal}
-			case "*":
-				return &object.IntObj{Value: leftVal * rightVal}
-			case "/":
-				return &object.IntObj{Value: leftVal / rightVal}
-			case "<":
-				return changeBoolObj(leftVal < rightVal)
-			case ">":
-				return changeBoolObj(leftVal > rightVal)
-			case "==":
-				return changeBoolObj(leftVal == rightVal)
-			case "!=":
-				return changeBoolObj(leftVal != rightVal)
-			default:
-				return newErrorObj("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
-			}
 
-		// 2.
-		case left.Type() == object.STRING && right.Type() == object.STRING:
-			if node.Operator != "+" && node.Operator != "==" && node.Operator != "!=" {
-				return newErrorObj("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
+		// &&と||は左だけで決まったら右は評価しない
+		// 真偽値にはしないで、決めた方の値をそのまま返す（x || "default" みたいに使える）
+		switch node.Operator {
+		case "&&":
+			if !isTruthy(left) {
+				return left
 			}
-
-			leftVal := left.(*object.StringObj).Value
-			rightVal := right.(*object.StringObj).Value
-			switch node.Operator {
-			case "+":
-				return &object.StringObj{Value: leftVal + rightVal}
-			case "==":
-				return changeBoolObj(leftVal == rightVal)
-			case "!=":
-				return changeBoolObj(leftVal != rightVal)
+			return Eval(node.Right, env)
+		case "||":
+			if isTruthy(left) {
+				return left
 			}
+			return Eval(node.Right, env)
+		}
 
-		// 3.
-		// オブジェクトを指し示すのにポインタ（参照）のみを使っていて、ポインタを比較すればいい
-		// 		ポインタ（配置されているメモリアドレス）を比較している
-		//  	オブジェクトは、整数かTRUEかFALSEかNULLだけ。整数は先に計算して、残りは参照だけ
-		//		整数や他のオブジェクトはポインタの比較を単純にするわけにはいかない（毎回新しく生成しているから）
-		case node.Operator == "==":
-			return changeBoolObj(left == right)
-		case node.Operator == "!=":
-			return changeBoolObj(left != right)
+		right := Eval(node.Right, env)
+		if isStopObj(right) {
+			return right
+		}
 
-		// 4.
-		case left.Type() != right.Type():
-			return newErrorObj("type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
+		// エラーには演算子の位置をつける
+		return withPos(evalInfix(node.Operator, left, right), node.Token.Pos)
 
-		default:
-			return newErrorObj("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
-		}
+	case *ast.MatchNode:
+		return evalMatch(node, env)
 
 	case *ast.IfNode:
 		condition := Eval(node.Condition, env)
-		if isErrorObj(condition) {
+		if isStopObj(condition) {
 			return condition
 		}
 
@@ -203,9 +229,26 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return NULL
 		}
 
+	case *ast.TernaryNode:
+		condition := Eval(node.Condition, env)
+		if isStopObj(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
+
 	case *ast.FunctionNode:
 		// けっこうそのままいれる
-		return &object.FunctionObj{Parameters: node.Parameters, Body: node.Body, Env: env}
+		return &object.FunctionObj{
+			Parameters: node.Parameters,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Body:       node.Body,
+			Env:        env,
+		}
 
 	case *ast.CallNode:
 		// Functionにあるのは変数として認識されている
@@ -214,8 +257,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return quote(node.Arguments[0])
 		}
 
-		function := Eval(node.Function, env)
-		if isErrorObj(function) {
+		var function object.Object
+		if dot, ok := node.Function.(*ast.DotNode); ok {
+			function = evalMethod(dot, env)
+		} else {
+			function = Eval(node.Function, env)
+		}
+		if isStopObj(function) {
 			return function
 		}
 
@@ -224,7 +272,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		// 引数を左から右に評価
 		for i, e := range node.Arguments {
 			obj := Eval(e, env)
-			if isErrorObj(obj) {
+			if isStopObj(obj) {
 				return obj
 			}
 			args[i] = obj
@@ -232,40 +280,25 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 		switch fn := function.(type) {
 		case *object.FunctionObj:
-			// パラメータを『拡張した環境』に束縛
-			extendedEnv := object.NewEnclosedEnvironment(fn.Env)
-			for paramIdx, param := range fn.Parameters {
-				// パラメータの変数 ← 評価結果
-				env.Set(param.Value, args[paramIdx])
-			}
-
-			// ボディと『拡張した環境』で評価
-			result := Eval(fn.Body, extendedEnv)
-
-			// もし、結果がReturnオブジェクトだったらそのまま返却
-			// その関数からのリターンだから、これはBlockの時みたいに上に上げなくていい
-			// むしろこのif文がないと、そのままReturnが浮上して処理が止まってしまう
-			if returnValue, ok := result.(*object.ReturnObj); ok {
-				return returnValue.Value
-			}
-
-			return result
+			// 引数の数のエラーには呼び出し位置をつける
+			return withPos(applyFunction(fn, args), node.Token.Pos)
 
 		// 絶対にReturnを返さないので、アンラップする必要がない
 		case *object.BuiltinObj:
 			// 引数の評価結果をそのまま渡す
 			// builtins.go内でよしなに処理
-			return fn.Fn(args...)
+			// 組込み関数のエラーには呼び出し位置をつける
+			return withPos(fn.Fn(args...), node.Token.Pos)
 
 		default:
-			return newErrorObj("not a function: %s", function.Type()) // 存在していないfn.Type()しててランタイムエラーになっていた
+			return withPos(newErrorObj("not a function: %s", function.Type()), node.Token.Pos) // 存在していないfn.Type()しててランタイムエラーになっていた
 		}
 
 	case *ast.ArrayNode:
 		values := make([]object.Object, len(node.Values))
 		for i, v := range node.Values {
 			obj := Eval(v, env)
-			if isErrorObj(obj) {
+			if isStopObj(obj) {
 				return obj
 			}
 			values[i] = obj
@@ -274,65 +307,35 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ArrayObj{Values: values}
 
 	case *ast.IndexNode:
-		left := Eval(node.Left, env)
-		if isErrorObj(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-		if isErrorObj(index) {
-			return index
-		}
-
-		switch {
-		case left.Type() == object.ARRAY && index.Type() == object.INT:
-			array := left.(*object.ArrayObj)
-			idx := index.(*object.IntObj).Value
-			max := int64(len(array.Values) - 1)
-
-			if idx < 0 || max < idx {
-				return NULL
-			}
-
-			return array.Values[idx]
+		return evalIndex(node, env)
 
-		case left.Type() == object.HASH:
-			hashObj := left.(*object.HashObj)
+	case *ast.SliceNode:
+		return evalSlice(node, env)
 
-			// インデックスはハッシュブルなはず
-			key, ok := index.(object.Hashable)
-			if !ok {
-				return newErrorObj("unusable as hash key: %s", index.Type())
-			}
-
-			// ペアを取得
-			pair, ok := hashObj.Pairs[key.HashKey()]
-			if !ok {
-				return NULL
-			}
-
-			return pair.Value
-
-		default:
-			return newErrorObj("index operator not supported: %s", left.Type())
+	case *ast.DotNode:
+		left := Eval(node.Left, env)
+		if isStopObj(left) {
+			return left
 		}
+		return evalDot(node, left)
 
 	case *ast.HashNode:
 		pairs := make(map[object.HashKey]object.HashPair)
 
 		for keyNode, valueNode := range node.Pairs {
 			key := Eval(keyNode, env)
-			if isErrorObj(key) {
+			if isStopObj(key) {
 				return key
 			}
 
 			// キーがハッシュ化可能かどうか
 			hashKey, ok := key.(object.Hashable)
 			if !ok {
-				return newErrorObj("unusable as hash key: %s", key.Type())
+				return withPos(newErrorObj("unusable as hash key: %s", key.Type()), node.Token.Pos)
 			}
 
 			value := Eval(valueNode, env)
-			if isErrorObj(value) {
+			if isStopObj(value) {
 				return value
 			}
 
@@ -348,6 +351,154 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
+func evalInfix(operator string, left, right object.Object) object.Object {
+	switch {
+
+	// 1.
+	// 先にInt型をやることで、「先に==や!=で変換して比較される」ことを防いでいる
+	case left.Type() == object.INT && right.Type() == object.INT:
+
+		// 値をオブジェクトからアンラップ
+		leftVal := left.(*object.IntObj).Value
+		rightVal := right.(*object.IntObj).Value
+
+		switch operator {
+		case "+":
+			return &object.IntObj{Value: leftVal + rightVal}
+		case "-":
+			return &object.IntObj{Value: leftVal - rightVal}
+		case "*":
+			return &object.IntObj{Value: leftVal * rightVal}
+		case "/":
+			// Goの整数の0除算はパニックしてしまう
+			if rightVal == 0 {
+				return newErrorObj("division by zero")
+			}
+			return &object.IntObj{Value: leftVal / rightVal}
+		case "%":
+			if rightVal == 0 {
+				return newErrorObj("division by zero")
+			}
+			return &object.IntObj{Value: leftVal % rightVal}
+		case "**":
+			// 負の指数は整数にならないので小数で返す
+			if rightVal < 0 {
+				return &object.FloatObj{Value: math.Pow(float64(leftVal), float64(rightVal))}
+			}
+			return &object.IntObj{Value: intPow(leftVal, rightVal)}
+		case "&":
+			return &object.IntObj{Value: leftVal & rightVal}
+		case "|":
+			return &object.IntObj{Value: leftVal | rightVal}
+		case "^":
+			return &object.IntObj{Value: leftVal ^ rightVal}
+		case "<<", ">>":
+			if rightVal < 0 {
+				return newErrorObj("negative shift count: %d", rightVal)
+			}
+			if operator == "<<" {
+				return &object.IntObj{Value: leftVal << rightVal}
+			}
+			return &object.IntObj{Value: leftVal >> rightVal}
+		case "<":
+			return changeBoolObj(leftVal < rightVal)
+		case ">":
+			return changeBoolObj(leftVal > rightVal)
+		case "<=":
+			return changeBoolObj(leftVal <= rightVal)
+		case ">=":
+			return changeBoolObj(leftVal >= rightVal)
+		case "==":
+			return changeBoolObj(leftVal == rightVal)
+		case "!=":
+			return changeBoolObj(leftVal != rightVal)
+		default:
+			return newErrorObj("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		}
+
+	// 2.
+	// 片方でも小数なら、整数を小数に揃えてから計算する（1 + 0.5 → 1.5）
+	case isNumberObj(left) && isNumberObj(right):
+
+		leftVal := toFloat(left)
+		rightVal := toFloat(right)
+
+		switch operator {
+		case "+":
+			return &object.FloatObj{Value: leftVal + rightVal}
+		case "-":
+			return &object.FloatObj{Value: leftVal - rightVal}
+		case "*":
+			return &object.FloatObj{Value: leftVal * rightVal}
+		case "/":
+			return &object.FloatObj{Value: leftVal / rightVal}
+		case "%":
+			return &object.FloatObj{Value: math.Mod(leftVal, rightVal)}
+		case "**":
+			return &object.FloatObj{Value: math.Pow(leftVal, rightVal)}
+		case "<":
+			return changeBoolObj(leftVal < rightVal)
+		case ">":
+			return changeBoolObj(leftVal > rightVal)
+		case "<=":
+			return changeBoolObj(leftVal <= rightVal)
+		case ">=":
+			return changeBoolObj(leftVal >= rightVal)
+		case "==":
+			return changeBoolObj(leftVal == rightVal)
+		case "!=":
+			return changeBoolObj(leftVal != rightVal)
+		default:
+			return newErrorObj("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		}
+
+	// 3.
+	case left.Type() == object.STRING && right.Type() == object.STRING:
+		leftVal := left.(*object.StringObj).Value
+		rightVal := right.(*object.StringObj).Value
+		switch operator {
+		case "+":
+			return &object.StringObj{Value: leftVal + rightVal}
+		case "==":
+			return changeBoolObj(leftVal == rightVal)
+		case "!=":
+			return changeBoolObj(leftVal != rightVal)
+		default:
+			return newErrorObj("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		}
+
+	// 4.
+	// オブジェクトを指し示すのにポインタ（参照）のみを使っていて、ポインタを比較すればいい
+	// 		ポインタ（配置されているメモリアドレス）を比較している
+	//  	オブジェクトは、整数かTRUEかFALSEかNULLだけ。整数は先に計算して、残りは参照だけ
+	//		整数や他のオブジェクトはポインタの比較を単純にするわけにはいかない（毎回新しく生成しているから）
+	case operator == "==":
+		return changeBoolObj(left == right)
+	case operator == "!=":
+		return changeBoolObj(left != right)
+
+	// 5.
+	case left.Type() != right.Type():
+		return newErrorObj("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+
+	default:
+		return newErrorObj("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// 整数か小数
+func isNumberObj(obj object.Object) bool {
+	return obj.Type() == object.INT || obj.Type() == object.FLOAT
+}
+
+// 整数も小数に揃える（isNumberObjで確認してから呼ぶ）
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.IntObj); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.FloatObj).Value
+}
+
 func changeBoolObj(value bool) object.Object {
 	if value {
 		return TRUE
@@ -378,6 +529,28 @@ func newErrorObj(format string, a ...interface{}) *object.ErrorObj {
 	return &object.ErrorObj{Value: fmt.Sprintf(format, a...)}
 }
 
+// エラーオブジェクトに位置がまだついていなかったらつける
+// 内側で起きたエラーはもう位置を持っているので上書きしない
+func withPos(obj object.Object, pos token.Position) object.Object {
+	if errObj, ok := obj.(*object.ErrorObj); ok && !errObj.Pos.IsValid() {
+		errObj.Pos = pos
+	}
+	return obj
+}
+
+// エラーとreturn, break, continueは値じゃない
+// 式の途中で出てきたら、変数に入れたり計算したりしないでそのまま上に上げる
+func isStopObj(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR, object.RETURN, object.BREAK, object.CONTINUE:
+		return true
+	}
+	return false
+}
+
 func isErrorObj(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR
@@ -403,15 +576,78 @@ func evalPrefix(operator string, right object.Object) object.Object {
 
 	// マイナス
 	case "-":
-		if right.Type() != object.INT {
+		switch right := right.(type) {
+		case *object.IntObj:
+			return &object.IntObj{Value: -right.Value}
+		case *object.FloatObj:
+			return &object.FloatObj{Value: -right.Value}
+		default:
 			return newErrorObj("unknown operator: -%s", right.Type())
 		}
 
-		value := right.(*object.IntObj).Value
-		return &object.IntObj{Value: -value}
+	// ビット反転（整数だけ）
+	case "~":
+		if right.Type() != object.INT {
+			return newErrorObj("unknown operator: ~%s", right.Type())
+		}
+		return &object.IntObj{Value: ^right.(*object.IntObj).Value}
 
 	default:
 		return newErrorObj("unknown operator: %s%s", operator, right.Type())
 	}
 
 }
+
+// 文の中の関数宣言を全部環境に登録する（巻き上げ）
+// 同じ環境に入るので、お互いに呼び合う関数も書ける
+func declareFunctions(statements []ast.Statement, env *object.Environment) {
+	for _, statement := range statements {
+		if decl, ok := statement.(*ast.FuncDeclNode); ok {
+			env.Set(decl.Name.Value, Eval(decl.Function, env))
+		}
+	}
+}
+
+// x = 1, x += 1
+// letと同じで値は返さない
+func evalAssign(node *ast.AssignNode, env *object.Environment) object.Object {
+	name := node.Name.Value
+
+	obj := Eval(node.Value, env)
+	if isStopObj(obj) {
+		return obj
+	}
+
+	// "+="なら今の値と"+"してから入れる
+	if node.Operator != "=" {
+		current, ok := env.Get(name)
+		if !ok {
+			return withPos(newErrorObj("identifier not found: %s", name), node.Name.Token.Pos)
+		}
+
+		operator := strings.TrimSuffix(node.Operator, "=")
+		obj = withPos(evalInfix(operator, current, obj), node.Token.Pos)
+		if isStopObj(obj) {
+			return obj
+		}
+	}
+
+	if _, ok := env.Assign(name, obj); !ok {
+		return withPos(newErrorObj("cannot assign to undefined variable: %s", name), node.Name.Token.Pos)
+	}
+
+	return nil
+}
+
+// 整数の累乗（繰り返し二乗法）。expは0以上
+func intPow(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
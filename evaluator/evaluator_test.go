@@ -28,6 +28,19 @@ func TestEvalInt(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"3 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"256 >> 4", 16},
+		{"1 + 2 << 1", 6},
 	}
 
 	for _, tt := range tests {
@@ -35,6 +48,30 @@ func TestEvalInt(t *testing.T) {
 	}
 }
 
+func TestEvalFloat(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"2 * 1e3", 2000},
+		{"-(1 - 1.25)", 0.25},
+		{"7.5 % 2", 1.5},
+		{"2 ** 0.5 ** 2", 1.189207115002721},
+		{"2 ** -1", 0.5},
+		{"4.0 ** 2", 16},
+	}
+
+	for _, tt := range tests {
+		testFloatObj(t, testEval(tt.input), tt.expect)
+	}
+}
+
 func TestEvalBool(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -63,6 +100,16 @@ func TestEvalBool(t *testing.T) {
 		{`"foobr" == "foobar"`, false},
 		{`"foobr" != "foobar"`, true},
 		{`"foobar" != "foobar"`, false},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 != 0.3", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1.5 <= 1", false},
+		{"1.5 >= 1.5", true},
 	}
 
 	for _, tt := range tests {
@@ -100,14 +147,34 @@ func TestIfElse(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"let f = fn(n) { if (n < 0) { return -1; } else if (n == 0) { return 0; } 1 }; f(-5) * 100 + f(0) * 10 + f(3)", -99},
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"let x = 5; x > 3 ? x * 2 : x", 10},
+		{"let sign = fn(n) { n < 0 ? -1 : n == 0 ? 0 : 1 }; sign(-3) * 100 + sign(0) * 10 + sign(7)", -99},
+		{"(if (false) { 1 }) ? 1 : undefinedThing", "identifier not found: undefinedThing"},
+		{"true ? 1 : undefinedThing", 1},
 	}
 
 	for _, tt := range tests {
 		obj := testEval(tt.input)
-		integer, ok := tt.expect.(int)
-		if ok {
-			testIntObj(t, obj, int64(integer))
-		} else {
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case string:
+			errObj, ok := obj.(*object.ErrorObj)
+			if !ok {
+				t.Errorf("no error object returned. got=%T (%+v)", obj, obj)
+				continue
+			}
+			if errObj.Value != expect {
+				t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+			}
+		default:
 			testNullObj(t, obj)
 		}
 	}
@@ -221,6 +288,38 @@ func TestError(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) {x}];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOL",
+		},
+		{
+			"-\"a\"",
+			"unknown operator: -STRING",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"1 % 0",
+			"division by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INT",
+		},
+		{
+			"~1.5",
+			"unknown operator: ~FLOAT",
+		},
+		{
+			`"a" <= "b"`,
+			"unknown operator: STRING <= STRING",
+		},
 	}
 
 	for _, tt := range tests {
@@ -336,6 +435,32 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{`let name = "monkey"; let age = 3; "hello ${name}, you are ${age}"`, "hello monkey, you are 3"},
+		{`"${1 + 2}${"a" + "b"}"`, "3ab"},
+		{`"${[1, "x", true]} ${2.5} ${if (false) { 1 }}"`, "[1, x, true] 2.5 null"},
+		{`let f = fn(x) { x * 2 }; "f(2) = ${f(2)}, nested: ${"in ${f(3)}"}"`, "f(2) = 4, nested: in 6"},
+		{`let h = {"k": "v"}; "${h["k"]}!"`, "v!"},
+		{`"a ${1 + true} b"`, "type mismatch: INT + BOOL"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		if errObj, ok := obj.(*object.ErrorObj); ok {
+			if errObj.Value != tt.expect {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expect, errObj.Value)
+			}
+			continue
+		}
+		testStringObj(t, obj, tt.expect)
+	}
+}
+
 func TestBuiltinFunction(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -459,6 +584,14 @@ func TestArrayIndex(t *testing.T) {
 		},
 		{
 			"[1,2,3][-1]",
+			3,
+		},
+		{
+			"[1,2,3][-3]",
+			1,
+		},
+		{
+			"[1,2,3][-4]",
 			nil,
 		},
 		{
@@ -490,6 +623,117 @@ func TestArrayIndex(t *testing.T) {
 	}
 }
 
+func TestSlice(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[][0:1]", []int{}},
+		{"let a = [1, 2, 3]; let b = a[:]; b = push(b, 4); len(a)", 3},
+		{`"hello"[0]`, "h"},
+		{`"hello"[-1]`, "o"},
+		{`"hello"[2:]`, "llo"},
+		{`"hello"[:2]`, "he"},
+		{`"日本語"[1:]`, "本語"},
+		{`"日本語"[-1]`, "語"},
+		{`"abc"[5:]`, ""},
+		{`"abc"[3]`, nil},
+		{`[1, 2]["a":]`, "index must be INT, got STRING"},
+		{`[1, 2][0:1.5]`, "index must be INT, got FLOAT"},
+		{`[1, 2][true]`, "index must be INT, got BOOL"},
+		{`{"a": 1}[0:1]`, "slice operator not supported: HASH"},
+		{`5[0:1]`, "slice operator not supported: INT"},
+		{"let f = fn() { let x = 1; }; f()[1:]", "slice operator not supported: NULL"},
+		{"let f = fn() { let x = 1; }; [1, 2][f():]", "index must be INT, got NULL"},
+		{"let f = fn() { let x = 1; }; f()[0]", "index operator not supported: NULL"},
+		{"let f = fn() { let x = 1; }; [1, 2][f()]", "index must be INT, got NULL"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case nil:
+			testNullObj(t, obj)
+		case []int:
+			array, ok := obj.(*object.ArrayObj)
+			if !ok {
+				t.Errorf("%q - obj is not ArrayObj. got=%T (%+v)", tt.input, obj, obj)
+				continue
+			}
+			if len(array.Values) != len(expect) {
+				t.Errorf("%q - wrong num of elements. want=%d, got=%d", tt.input, len(expect), len(array.Values))
+				continue
+			}
+			for i, v := range expect {
+				testIntObj(t, array.Values[i], int64(v))
+			}
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestDotAccess(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{`let p = {"name": "monkey", "age": 3}; p.age`, 3},
+		{`let p = {"name": "monkey"}; p.name`, "monkey"},
+		{`let p = {"name": "monkey"}; p.missing`, nil},
+		{`let p = {"inner": {"xs": [1, 2, 3]}}; p.inner.xs[-1]`, 3},
+		{`let c = {"n": 10, "add": fn(x) { self.n + x }}; c.add(5)`, 15},
+		{`let c = {"n": 1, "twice": fn() { self.get() * 2 }, "get": fn() { self.n }}; c.twice()`, 2},
+		{`let c = {"f": fn(x, y) { x - y }}; 10 |> c.f(3)`, 7},
+		{`let c = {"f": len}; c.f([1, 2])`, 2},
+		// 取り出しただけの関数はselfを持たない
+		{`let c = {"f": fn() { self }}; let f = c.f; f()`, "identifier not found: self"},
+		{`let self = 1; let c = {"f": fn() { self }}; let f = c.f; f()`, 1},
+		{`let c = {"n": 1}; c.n()`, "not a function: INT"},
+		{`let c = {}; c.nothing()`, "not a function: NULL"},
+		{`[1, 2].len`, "dot access not supported: ARRAY"},
+		{`5.x()`, "dot access not supported: INT"},
+		{"let f = fn() { let x = 1; }; f().a", "dot access not supported: NULL"},
+		{"let f = fn() { let x = 1; }; f().a()", "dot access not supported: NULL"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case nil:
+			testNullObj(t, obj)
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
 func TestHash(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -563,6 +807,26 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{1.5: 5}[1.5]`,
+			5,
+		},
+		{
+			`{1.5: 5}[1]`,
+			nil,
+		},
+		{
+			`{1.0: 5}[1]`,
+			5,
+		},
+		{
+			`{2: 5}[2.0]`,
+			5,
+		},
+		{
+			`let n = 0; for (k in {1: 5, 1.0: 6}) { n += 1; } n`,
+			1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -604,6 +868,21 @@ func testIntObj(t *testing.T, obj object.Object, expect int64) bool {
 	return true
 }
 
+func testFloatObj(t *testing.T, obj object.Object, expect float64) bool {
+	result, ok := obj.(*object.FloatObj)
+	if !ok {
+		t.Errorf("object is not FloatObj. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expect {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expect)
+		return false
+	}
+
+	return true
+}
+
 func testBoolObj(t *testing.T, obj object.Object, expect bool) bool {
 	result, ok := obj.(*object.BoolObj)
 	if !ok {
@@ -619,6 +898,21 @@ func testBoolObj(t *testing.T, obj object.Object, expect bool) bool {
 	return true
 }
 
+func testStringObj(t *testing.T, obj object.Object, expect string) bool {
+	result, ok := obj.(*object.StringObj)
+	if !ok {
+		t.Errorf("object is not StringObj. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expect {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expect)
+		return false
+	}
+
+	return true
+}
+
 func testNullObj(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -626,3 +920,479 @@ func testNullObj(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"5 + true;", "ERROR: script.mk:1:3: type mismatch: INT + BOOL"},
+		{"let x = 1;\n  foobar;", "ERROR: script.mk:2:3: identifier not found: foobar"},
+		{"-true", "ERROR: script.mk:1:1: unknown operator: -BOOL"},
+		{"let f = fn(x) {\n  x + true\n};\nf(1)", "ERROR: script.mk:2:5: type mismatch: INT + BOOL"},
+		{`len(1)`, "ERROR: script.mk:1:4: argument to `len` not supported, got INT"},
+		// 構文エラーが残ったASTを評価したとき
+		{"let x = 1;\nlet = 2;\nx", "ERROR: script.mk:2:1: invalid syntax"},
+		{"let a = [1, 2];\na[\"0\"]", "ERROR: script.mk:2:2: index must be INT, got STRING"},
+		{`"abc"[0:true]`, "ERROR: script.mk:1:6: index must be INT, got BOOL"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, lexer.WithFile("script.mk"))
+		p := parser.NewParser(l)
+		program := p.ParseProgram()
+		obj := Eval(program, object.NewEnvironment())
+
+		errObj, ok := obj.(*object.ErrorObj)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", obj, obj)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errObj.Inspect())
+		}
+	}
+}
+
+func TestUnicodeString(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{`len("日本")`, 2},
+		{`bytelen("日本")`, 6},
+		{`bytelen("abc")`, 3},
+		{`bytelen(1)`, "argument to `bytelen` must be STRING, got INT"},
+		{`let 名前 = "モンキー"; len(名前)`, 4},
+		{`"日本語"[1]`, "本"},
+		{`"日本語"[3]`, nil},
+		{`first("日本")`, "日"},
+		{`last("日本")`, "本"},
+		{`rest("日本語")`, "本語"},
+		{`first("")`, nil},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case nil:
+			testNullObj(t, obj)
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"let i = 0; while (i < 5) { let i = i + 1; } i", 5},
+		{"let i = 0; while (false) { let i = i + 1; } i", 0},
+		{"while (false) { 1 }", nil},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 3) { break; } } i", 3},
+		{"let sum = 0; for (x in [1, 2, 3, 4, 5]) { if (x == 2) { continue; } if (x == 5) { break; } let sum = sum + x; } sum", 8},
+		{`let s = ""; for (c in "日本語") { let s = c + s; } s`, "語本日"},
+		{`let s = ""; for (k in {"b": 1, "a": 2, "c": 3}) { let s = s + k; } s`, "abc"},
+		{`let s = 0; for (k in {10: 1, 9: 2, -1: 3}) { let s = s * 100 + k * 2; } s`, -18180},
+		{"for (x in [1, 2]) { x }", nil},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } return 0; }; f()", 20},
+		{"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i > 3) { return i; } } }; f()", 4},
+		{"for (x in [[1, 2], [3]]) { for (y in x) { break; } }", nil},
+		{"for (x in 5) { x }", "cannot iterate over INT"},
+		{"let f = fn() { let x = 1; }; for (x in f()) {}", "cannot iterate over NULL"},
+		{"for (x in [1]) { x + true }", "type mismatch: INT + BOOL"},
+		{"break;", "break outside loop"},
+		{"if (true) { continue; }", "continue outside loop"},
+		{"for (x in [1]) { let f = fn() { break; }; f(); }", "break outside loop"},
+		// 式の中のbreak, continue, returnは値にならずにそのまま上がる
+		{"let i = 0; while (true) { i += 1; let x = if (true) { break; }; } i", 1},
+		{"let i = 0; while (true) { i += 1; puts(if (true) { break; }); } i", 1},
+		{"let n = 0; for (x in [1, 2, 3]) { n = n + if (x == 2) { continue; } else { x }; } n", 4},
+		{"let n = 0; for (x in [1, 2, 3]) { let a = [x, if (x == 2) { break; }]; n += x; } n", 1},
+		{`let n = 0; for (x in [1, 2, 3]) { let h = {"k": if (x > 1) { break; }}; n += x; } n`, 1},
+		{"let f = fn() { let x = if (true) { return 7; }; 0 }; f()", 7},
+		{"while (if (true) { break; }) { 1 }", "break outside loop"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case nil:
+			testNullObj(t, obj)
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2; x", 3},
+		{"let x = 10; x -= 2; x *= 3; x /= 4; x", 6},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i; } sum", 15},
+		// クロージャから外の変数を書き換えられる
+		{"let counter = fn() { let n = 0; fn() { n += 1; n } }; let c = counter(); c(); c(); c()", 3},
+		{"let x = 1; let f = fn() { x = 5; }; f(); x", 5},
+		// 引数は呼び出した側の環境を書き換えない
+		{"let x = 1; let f = fn(x) { x = 5; x }; f(2) + x", 6},
+		{"y = 1", "cannot assign to undefined variable: y"},
+		{"y += 1", "identifier not found: y"},
+		{"let x = 1; x += true", "type mismatch: INT + BOOL"},
+		{"let x = 1; x /= 0", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		// 決めた方の値をそのまま返す
+		{"1 && 2", 2},
+		{"0 || 5", 0},
+		{`let name = if (false) { "x" }; name || "default"`, "default"},
+		{"false && 1", false},
+		// 右は評価しない
+		{"false && undefinedThing", false},
+		{"true || undefinedThing", true},
+		{"let x = 0; let f = fn() { x = 1; true }; false && f(); x", 0},
+		{"true && undefinedThing", "identifier not found: undefinedThing"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case bool:
+			testBoolObj(t, obj, expect)
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"[1, 2, 3] |> len", 3},
+		{"[1, 2] |> push(3) |> rest |> last", 3},
+		{`fn map(xs, f) { let out = []; for (x in xs) { out = push(out, f(x)); } out }
+		  fn sum(xs) { let s = 0; for (x in xs) { s += x; } s }
+		  [1, 2, 3] |> map(fn(x) { x * x }) |> sum`, 14},
+		{"1 + 2 |> fn(x) { x * 10 }", 30},
+		{"let add = fn(a, b) { a + b }; 1 |> add(2) |> add(3) == 6", true},
+		{"let adder = fn(n) { fn(x) { x + n } }; 1 |> (adder(10))", 11},
+		{"1 |> 2", "not a function: INT"},
+		{"[1] |> push", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case bool:
+			testBoolObj(t, obj, expect)
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestArrowFunction(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"let double = x => x * 2; double(4)", 8},
+		{"((x, y) => x + y)(2, 3)", 5},
+		{"(() => 7)()", 7},
+		{"let add = x => y => x + y; add(1)(2)", 3},
+		{"((a, b = 10) => a + b)(1)", 11},
+		{"((...xs) => len(xs))(1, 2, 3)", 3},
+		{`fn map(xs, f) { let out = []; for (x in xs) { out = push(out, f(x)); } out }
+		  [1, 2, 3] |> map(x => x * 10) |> last`, 30},
+		{`let f = x => match (x) { 0 => "zero", n if n > 0 => "pos", _ => "neg" }; f(-1)`, "neg"},
+		{"(x => x)(1, 2)", "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestFuncDecl(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"fn add(a, b) { a + b } add(1, 2)", 3},
+		// 宣言より前で呼べる
+		{"let x = double(4); fn double(n) { n * 2 } x", 8},
+		// お互いに呼び合う
+		{`
+fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+isEven(10)
+`, true},
+		{"fn fact(n) { if (n < 2) { return 1; } n * fact(n - 1) } fact(5)", 120},
+		// ブロックの中でも巻き上げる
+		{"let f = fn() { return g(); fn g() { 7 } }; f()", 7},
+		{"fn outer() { fn inner() { 1 } inner() + 1 } outer()", 2},
+		{"fn f() { 1 }", nil},
+		{"inner()", "identifier not found: inner"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case bool:
+			testBoolObj(t, obj, expect)
+		case nil:
+			if obj != nil {
+				t.Errorf("expected no value. got=%T (%+v)", obj, obj)
+			}
+		case string:
+			errObj, ok := obj.(*object.ErrorObj)
+			if !ok {
+				t.Errorf("no error object returned. got=%T (%+v)", obj, obj)
+				continue
+			}
+			if errObj.Value != expect {
+				t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+			}
+		}
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"let add = fn(a, b) { a + b }; add(1)", "wrong number of arguments. got=1, want=2"},
+		{"let add = fn(a, b) { a + b }; add(1, 2, 3)", "wrong number of arguments. got=3, want=2"},
+		{"fn f() { 1 } f(1)", "wrong number of arguments. got=1, want=0"},
+		{"let add = fn(a, b = 10) { a + b }; add(1)", 11},
+		{"let add = fn(a, b = 10) { a + b }; add(1, 2)", 3},
+		{"let add = fn(a, b = 10) { a + b }; add()", "wrong number of arguments. got=0, want=1..2"},
+		// デフォルト値は前の引数を使えて、呼ぶたびに評価される
+		{"let f = fn(a, b = a * 2) { b }; f(4)", 8},
+		{"let n = 1; let f = fn(a = n) { a }; n = 5; f()", 5},
+		{"let f = fn(a = undefinedThing) { a }; f()", "identifier not found: undefinedThing"},
+		{"let f = fn(first, ...rest) { len(rest) }; f(1, 2, 3)", 2},
+		{"let f = fn(first, ...rest) { len(rest) }; f(1)", 0},
+		{"let f = fn(...all) { all[1] }; f(1, 2, 3)", 2},
+		{"let f = fn(a, b = 2, ...rest) { a + b + len(rest) }; f(1)", 3},
+		{"let f = fn(a, b = 2, ...rest) { a + b + len(rest) }; f(1, 5, 0, 0)", 8},
+		{"let f = fn(a, ...rest) { a }; f()", "wrong number of arguments. got=0, want=1+"},
+		// 引数は関数の中の環境に入る（外の同じ名前の変数は変わらない）
+		{"let x = 1; let f = fn(x) { x * 10 }; f(5); x", 1},
+		{"let x = 1; let f = fn(a, x = 7) { a + x }; f(1); x", 1},
+		{"let x = 1; let f = fn(...x) { len(x) }; f(1, 2); x", 1},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case string:
+			errObj, ok := obj.(*object.ErrorObj)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T (%+v)", tt.input, obj, obj)
+				continue
+			}
+			if errObj.Value != expect {
+				t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+			}
+		}
+	}
+}
+
+func TestFunctionInspect(t *testing.T) {
+	obj := testEval("fn(a, b = 2, ...rest) { a }")
+
+	fn, ok := obj.(*object.FunctionObj)
+	if !ok {
+		t.Fatalf("object is not FunctionObj. got=%T (%+v)", obj, obj)
+	}
+
+	expect := "fn(a, b = 2, ...rest) {\n  a\n}"
+	if fn.Inspect() != expect {
+		t.Errorf("wrong Inspect. expect=%q, got=%q", expect, fn.Inspect())
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{`match (1) { 0 => "zero", 1 => "one", _ => "many" }`, "one"},
+		{`match (5) { 0 => "zero", 1 => "one", _ => "many" }`, "many"},
+		{`match (-2) { -2 => 1, _ => 0 }`, 1},
+		{`match (2.0) { 2 => 1, _ => 0 }`, 1},
+		{`match ("a") { 1 => 1, "a" => 2 }`, 2},
+		{`match (true) { false => 0, true => 1 }`, 1},
+		{`match (3) { 1 => 1, 2 => 2 }`, nil},
+		{`match ([1, 2, 3]) { [] => 0, [a] => a, [a, b, ...rest] => a + b + len(rest) * 100 }`, 103},
+		{`match ([1, [2, 3]]) { [_, [x, 3]] => x }`, 2},
+		{`match ([1, 2]) { [1, 3] => 0, [1, x] => x }`, 2},
+		{`match ({"type": "circle", "r": 3}) { {"type": "square", size} => size, {"type": "circle", r} => r * 10 }`, 30},
+		{`match (5) { x if x > 10 => "big", x if x > 0 => "small", _ => "neg" }`, "small"},
+		{`match (5) { [a] => a, {a} => a }`, nil},
+		{`let x = 1; match (7) { x => x }; x`, 1},
+		{`let f = fn(n) { match (n) { 0 => 1, _ => n * f(n - 1) } }; f(5)`, 120},
+		{`let f = fn(v) { let r = match (v) { [x] => x * 2, _ => 0 }; r + 1 }; f([4]) + f(4)`, 10},
+		{`match (1 + true) { _ => 1 }`, "type mismatch: INT + BOOL"},
+		{`match (1) { x if x + true => 1 }`, "type mismatch: INT + BOOL"},
+		{`let f = fn() { let x = 1; }; match (f()) { 1 => 1, _ => 2 }`, 2},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case nil:
+			testNullObj(t, obj)
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let pair = fn() { [3, 4] }; let [x, y] = pair(); x + y", 7},
+		{"let [first, ...rest] = [1, 2, 3]; first + len(rest) * 10", 21},
+		{"let [first, ...rest] = [1]; len(rest)", 0},
+		{"let [...all] = [1, 2]; all[1]", 2},
+		{`let {name, age} = {"name": "monkey", "age": 3, "extra": true}; name + " " + "x"`, "monkey x"},
+		{`let {age} = {"age": 3}; age`, 3},
+		{"let [a, b] = [1, 2, 3];", "array destructuring mismatch: want 2 elements, got 3"},
+		{"let [a, b] = [1];", "array destructuring mismatch: want 2 elements, got 1"},
+		{"let [a, b, ...c] = [1];", "array destructuring mismatch: want at least 2 elements, got 1"},
+		{"let [a] = 5;", "cannot destructure INT as ARRAY"},
+		{`let {name} = [1];`, "cannot destructure ARRAY as HASH"},
+		{`let {name, age} = {"name": "x"};`, `key "age" not found in hash`},
+		{"let [a, [b, _]] = [1, [2, 3]]; a + b", 3},
+		{`let {"x": [a, b]} = {"x": [4, 5]}; a * b`, 20},
+		{"let [0, a] = [0, 7]; a", 7},
+		{"let [0, a] = [1, 7];", "value 1 does not match pattern 0"},
+		{"let f = fn() { let x = 1; }; let [q] = f();", "cannot destructure NULL as ARRAY"},
+		{"let f = fn() { let x = 1; }; let {q} = f();", "cannot destructure NULL as HASH"},
+	}
+
+	for _, tt := range tests {
+		obj := testEval(tt.input)
+
+		switch expect := tt.expect.(type) {
+		case int:
+			testIntObj(t, obj, int64(expect))
+		case string:
+			if errObj, ok := obj.(*object.ErrorObj); ok {
+				if errObj.Value != expect {
+					t.Errorf("wrong error message. expected=%q, got=%q", expect, errObj.Value)
+				}
+				continue
+			}
+			testStringObj(t, obj, expect)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/yuya-isaka/go-yuya-monkey/ast"
+	"github.com/yuya-isaka/go-yuya-monkey/object"
+)
+
+// 関数を呼ぶ（引数は評価済み）
+func applyFunction(fn *object.FunctionObj, args []object.Object) object.Object {
+	// パラメータを『拡張した環境』に束縛
+	extendedEnv, errObj := extendFunctionEnv(fn, args)
+	if errObj != nil {
+		return errObj
+	}
+
+	// ボディと『拡張した環境』で評価
+	result := Eval(fn.Body, extendedEnv)
+
+	// もし、結果がReturnオブジェクトだったらそのまま返却
+	// その関数からのリターンだから、これはBlockの時みたいに上に上げなくていい
+	// むしろこのif文がないと、そのままReturnが浮上して処理が止まってしまう
+	if returnValue, ok := result.(*object.ReturnObj); ok {
+		return returnValue.Value
+	}
+
+	// 関数の外のループは抜けられない
+	switch result.(type) {
+	case *object.BreakObj, *object.ContinueObj:
+		return loopControlError(result)
+	}
+
+	return result
+}
+
+// h.method(args) の h.method
+// 関数なら、selfでhが見える環境で呼ばれるようにしたコピーを返す（h.methodの中身は変えない）
+func evalMethod(node *ast.DotNode, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isStopObj(left) {
+		return left
+	}
+
+	value := evalDot(node, left)
+	fn, ok := value.(*object.FunctionObj)
+	if !ok {
+		return value
+	}
+
+	selfEnv := object.NewEnclosedEnvironment(fn.Env)
+	selfEnv.Set("self", left)
+
+	method := *fn
+	method.Env = selfEnv
+	return &method
+}
+
+// 引数を関数の環境に入れる
+// 足りない引数はデフォルト値、多い引数は...restに入れる。どっちもできなければエラー
+func extendFunctionEnv(fn *object.FunctionObj, args []object.Object) (*object.Environment, object.Object) {
+	required := 0
+	for i := range fn.Parameters {
+		if defaultValue(fn, i) == nil {
+			required += 1
+		}
+	}
+
+	if len(args) < required || (fn.Rest == nil && len(args) > len(fn.Parameters)) {
+		return nil, newErrorObj("wrong number of arguments. got=%d, want=%s", len(args), arity(fn, required))
+	}
+
+	// 呼んだ側の環境じゃなくて、関数用に作った環境に入れる
+	// （呼んだ側に入れると、外の同じ名前の変数を書き換えてしまう）
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for i, param := range fn.Parameters {
+		// パラメータの変数 ← 評価結果
+		if i < len(args) {
+			env.Set(param.Value, args[i])
+			continue
+		}
+
+		// デフォルト値は呼ぶたびに関数の中で評価する（前の引数を使える: fn(a, b = a * 2)）
+		obj := Eval(defaultValue(fn, i), env)
+		if isErrorObj(obj) {
+			return nil, obj
+		}
+		env.Set(param.Value, obj)
+	}
+
+	// 残りは配列で（なければ空の配列）
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.ArrayObj{Values: rest})
+	}
+
+	return env, nil
+}
+
+// i番目の引数のデフォルト値（なければnil）
+func defaultValue(fn *object.FunctionObj, i int) ast.Expression {
+	if i < len(fn.Defaults) {
+		return fn.Defaults[i]
+	}
+	return nil
+}
+
+// 受け取れる引数の数（2, 1..2, 1+ みたいな形）
+func arity(fn *object.FunctionObj, required int) string {
+	switch {
+	case fn.Rest != nil:
+		return fmt.Sprintf("%d+", required)
+	case required != len(fn.Parameters):
+		return fmt.Sprintf("%d..%d", required, len(fn.Parameters))
+	default:
+		return fmt.Sprintf("%d", required)
+	}
+}
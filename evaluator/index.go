@@ -0,0 +1,195 @@
+package evaluator
+
+import (
+	"github.com/yuya-isaka/go-yuya-monkey/ast"
+	"github.com/yuya-isaka/go-yuya-monkey/object"
+	"github.com/yuya-isaka/go-yuya-monkey/token"
+)
+
+// 配列と文字列は同じルール（文字列はバイトじゃなくて文字で数える）
+//   - 負の数は後ろから数える（-1が最後）
+//   - x[i] は範囲外ならNULL
+//   - x[a:b] は範囲外を端に寄せる（エラーにもNULLにもならない）
+
+func evalIndex(node *ast.IndexNode, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isStopObj(left) {
+		return left
+	}
+	index := Eval(node.Index, env)
+	if isStopObj(index) {
+		return index
+	}
+	// 値がない式はNULL
+	if left == nil {
+		left = NULL
+	}
+	if index == nil {
+		index = NULL
+	}
+
+	switch left := left.(type) {
+	case *object.ArrayObj:
+		idx, errObj := indexInt(index, len(left.Values), node.Token.Pos)
+		if errObj != nil {
+			return errObj
+		}
+		if idx < 0 || len(left.Values) <= idx {
+			return NULL
+		}
+		return left.Values[idx]
+
+	case *object.StringObj:
+		chars := []rune(left.Value)
+		idx, errObj := indexInt(index, len(chars), node.Token.Pos)
+		if errObj != nil {
+			return errObj
+		}
+		if idx < 0 || len(chars) <= idx {
+			return NULL
+		}
+		return &object.StringObj{Value: string(chars[idx])}
+
+	case *object.HashObj:
+		// インデックスはハッシュブルなはず
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return withPos(newErrorObj("unusable as hash key: %s", index.Type()), node.Token.Pos)
+		}
+
+		// ペアを取得
+		pair, ok := left.Pairs[key.HashKey()]
+		if !ok {
+			return NULL
+		}
+
+		return pair.Value
+	}
+
+	return withPos(newErrorObj("index operator not supported: %s", left.Type()), node.Token.Pos)
+}
+
+// h.key は h["key"]（キーがなければNULL）
+func evalDot(node *ast.DotNode, left object.Object) object.Object {
+	// 値がない式はNULL
+	if left == nil {
+		left = NULL
+	}
+	hash, ok := left.(*object.HashObj)
+	if !ok {
+		return withPos(newErrorObj("dot access not supported: %s", left.Type()), node.Token.Pos)
+	}
+
+	pair, ok := hash.Pairs[(&object.StringObj{Value: node.Key.Value}).HashKey()]
+	if !ok {
+		return NULL
+	}
+
+	return pair.Value
+}
+
+func evalSlice(node *ast.SliceNode, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isStopObj(left) {
+		return left
+	}
+	if left == nil {
+		left = NULL
+	}
+
+	// 省略されたらnil（端まで）
+	// 書いてあるのに値がない式は、省略と区別するためにNULL
+	var start, end object.Object
+	if node.Start != nil {
+		if start = Eval(node.Start, env); isStopObj(start) {
+			return start
+		}
+		if start == nil {
+			start = NULL
+		}
+	}
+	if node.End != nil {
+		if end = Eval(node.End, env); isStopObj(end) {
+			return end
+		}
+		if end == nil {
+			end = NULL
+		}
+	}
+
+	switch left := left.(type) {
+	case *object.ArrayObj:
+		from, to, errObj := sliceBounds(node, start, end, len(left.Values))
+		if errObj != nil {
+			return errObj
+		}
+		// 元の配列とは別物
+		values := make([]object.Object, to-from)
+		copy(values, left.Values[from:to])
+		return &object.ArrayObj{Values: values}
+
+	case *object.StringObj:
+		chars := []rune(left.Value)
+		from, to, errObj := sliceBounds(node, start, end, len(chars))
+		if errObj != nil {
+			return errObj
+		}
+		return &object.StringObj{Value: string(chars[from:to])}
+	}
+
+	return withPos(newErrorObj("slice operator not supported: %s", left.Type()), node.Token.Pos)
+}
+
+// 整数のインデックスを取り出して、負の数なら後ろから数えた位置に直す
+// 範囲に入っているかは見ない
+func indexInt(index object.Object, length int, pos token.Position) (int, *object.ErrorObj) {
+	i, ok := index.(*object.IntObj)
+	if !ok {
+		errObj := newErrorObj("index must be INT, got %s", index.Type())
+		errObj.Pos = pos
+		return 0, errObj
+	}
+
+	idx := int(i.Value)
+	if idx < 0 {
+		idx += length
+	}
+	return idx, nil
+}
+
+// x[a:b] の範囲を 0 <= from <= to <= length に収める
+func sliceBounds(node *ast.SliceNode, start, end object.Object, length int) (int, int, *object.ErrorObj) {
+	from, to := 0, length
+
+	if start != nil {
+		idx, errObj := indexInt(start, length, node.Token.Pos)
+		if errObj != nil {
+			return 0, 0, errObj
+		}
+		from = clamp(idx, 0, length)
+	}
+	if end != nil {
+		idx, errObj := indexInt(end, length, node.Token.Pos)
+		if errObj != nil {
+			return 0, 0, errObj
+		}
+		to = clamp(idx, 0, length)
+	}
+
+	// 逆向きは空
+	if to < from {
+		to = from
+	}
+
+	return from, to, nil
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
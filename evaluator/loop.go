@@ -0,0 +1,142 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/yuya-isaka/go-yuya-monkey/ast"
+	"github.com/yuya-isaka/go-yuya-monkey/object"
+)
+
+// ループはどっちもNULLを返す
+// 中でreturnしたらReturnObjのまま上に上げる（関数の呼び出しのところで取り出される）
+
+func evalWhile(node *ast.WhileNode, env *object.Environment) object.Object {
+	for {
+		// 条件の中のbreak, continueはこのループのものではない
+		condition := Eval(node.Condition, env)
+		if isStopObj(condition) {
+			return loopControlError(condition)
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		if stop, obj := evalLoopBody(node.Body, env); stop {
+			return obj
+		}
+	}
+}
+
+func evalFor(node *ast.ForNode, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isStopObj(iterable) {
+		return loopControlError(iterable)
+	}
+	// 値がない式はNULL（回せないのでエラー）
+	if iterable == nil {
+		iterable = NULL
+	}
+
+	var items []object.Object
+
+	switch iterable := iterable.(type) {
+	case *object.ArrayObj:
+		// ループの中で配列をいじっても回る回数は変わらない
+		items = append(items, iterable.Values...)
+
+	// 文字列は1文字ずつ
+	case *object.StringObj:
+		for _, ch := range iterable.Value {
+			items = append(items, &object.StringObj{Value: string(ch)})
+		}
+
+	// ハッシュはキー（毎回同じ順番になるように並べる）
+	case *object.HashObj:
+		items = sortedKeys(iterable)
+
+	default:
+		return withPos(newErrorObj("cannot iterate over %s", iterable.Type()), node.Token.Pos)
+	}
+
+	for _, item := range items {
+		// ループ変数は外の環境に入れる（ifと同じでブロックのスコープはない）
+		env.Set(node.Variable.Value, item)
+
+		if stop, obj := evalLoopBody(node.Body, env); stop {
+			return obj
+		}
+	}
+
+	return NULL
+}
+
+// ループの中身を1回評価する
+// ループを終わるならtrueと、ループの結果として返すもの
+func evalLoopBody(body *ast.BlockNode, env *object.Environment) (bool, object.Object) {
+	obj := Eval(body, env)
+
+	switch obj.(type) {
+	case *object.BreakObj:
+		return true, NULL
+	case *object.ContinueObj:
+		return false, nil
+	case *object.ReturnObj, *object.ErrorObj:
+		return true, obj
+	}
+
+	return false, nil
+}
+
+// ループの外に出てしまったbreak, continue
+func loopControlError(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.BreakObj:
+		return withPos(newErrorObj("break outside loop"), obj.Pos)
+	case *object.ContinueObj:
+		return withPos(newErrorObj("continue outside loop"), obj.Pos)
+	}
+	return obj
+}
+
+// ハッシュのキーを並べる
+// 真偽値 → 数字（整数も小数もまとめて大きさ順） → 文字列 の順
+func sortedKeys(hash *object.HashObj) []object.Object {
+	keys := make([]object.Object, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		keys = append(keys, pair.Key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return lessKey(keys[i], keys[j])
+	})
+
+	return keys
+}
+
+func lessKey(a, b object.Object) bool {
+	ra, rb := keyRank(a), keyRank(b)
+	if ra != rb {
+		return ra < rb
+	}
+
+	switch a := a.(type) {
+	case *object.BoolObj:
+		return !a.Value && b.(*object.BoolObj).Value
+	case *object.StringObj:
+		return a.Value < b.(*object.StringObj).Value
+	}
+
+	return toFloat(a) < toFloat(b)
+}
+
+func keyRank(obj object.Object) int {
+	switch obj.Type() {
+	case object.BOOL:
+		return 0
+	case object.INT, object.FLOAT:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,142 @@
+package evaluator
+
+import (
+	"github.com/yuya-isaka/go-yuya-monkey/ast"
+	"github.com/yuya-isaka/go-yuya-monkey/object"
+	"github.com/yuya-isaka/go-yuya-monkey/token"
+)
+
+// let [a, b] = ..., let {name} = ... の値をばらして環境に登録する
+// 形が合わなければエラー（そのときは1つも登録しない）
+func bindPattern(pattern ast.Pattern, obj object.Object, env *object.Environment) object.Object {
+	binds := map[string]object.Object{}
+	if errObj := matchPattern(pattern, obj, binds, env); errObj != nil {
+		return errObj
+	}
+
+	for name, val := range binds {
+		env.Set(name, val)
+	}
+
+	return nil
+}
+
+// match (値) { パターン => 式, ... }
+// 上から順に試して、最初に合った（ifがあればそれも満たす）腕の値
+// どれにも合わなければNULL
+func evalMatch(node *ast.MatchNode, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isStopObj(subject) {
+		return subject
+	}
+	// 値がない式（letで終わる関数など）はNULLとして比べる
+	if subject == nil {
+		subject = NULL
+	}
+
+	for _, arm := range node.Arms {
+		binds := map[string]object.Object{}
+		if matchPattern(arm.Pattern, subject, binds, env) != nil {
+			continue
+		}
+
+		// 受け取った変数はこの腕の中だけ
+		armEnv := object.NewEnclosedEnvironment(env)
+		for name, val := range binds {
+			armEnv.Set(name, val)
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isStopObj(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return NULL
+}
+
+// パターンと値を照らし合わせる
+// 合えば受け取る変数をbindsに入れてnil、合わなければどこが合わないかのエラー
+func matchPattern(pattern ast.Pattern, obj object.Object, binds map[string]object.Object, env *object.Environment) *object.ErrorObj {
+	switch pattern := pattern.(type) {
+	// _ は何でも合って、どこにも登録しない
+	case *ast.IdentNode:
+		if pattern.Value != "_" {
+			binds[pattern.Value] = obj
+		}
+		return nil
+
+	case *ast.LiteralPatternNode:
+		lit := Eval(pattern.Value, env)
+		if isErrorObj(lit) {
+			return lit.(*object.ErrorObj)
+		}
+		if evalInfix("==", obj, lit) != TRUE {
+			return patternError(pattern.Token, "value %s does not match pattern %s", obj.Inspect(), pattern.String())
+		}
+		return nil
+
+	case *ast.ArrayPatternNode:
+		array, ok := obj.(*object.ArrayObj)
+		if !ok {
+			return patternError(pattern.Token, "cannot destructure %s as ARRAY", obj.Type())
+		}
+
+		n := len(pattern.Elements)
+		if pattern.Rest == nil && len(array.Values) != n {
+			return patternError(pattern.Token, "array destructuring mismatch: want %d elements, got %d", n, len(array.Values))
+		}
+		if pattern.Rest != nil && len(array.Values) < n {
+			return patternError(pattern.Token, "array destructuring mismatch: want at least %d elements, got %d", n, len(array.Values))
+		}
+
+		for i, elem := range pattern.Elements {
+			if errObj := matchPattern(elem, array.Values[i], binds, env); errObj != nil {
+				return errObj
+			}
+		}
+
+		// 残りは新しい配列に（元の配列とは別物）
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			rest := make([]object.Object, len(array.Values)-n)
+			copy(rest, array.Values[n:])
+			binds[pattern.Rest.Value] = &object.ArrayObj{Values: rest}
+		}
+
+		return nil
+
+	case *ast.HashPatternNode:
+		hash, ok := obj.(*object.HashObj)
+		if !ok {
+			return patternError(pattern.Token, "cannot destructure %s as HASH", obj.Type())
+		}
+
+		// パターンにないキーがあってもいい
+		for i, key := range pattern.Keys {
+			pair, ok := hash.Pairs[(&object.StringObj{Value: key.Value}).HashKey()]
+			if !ok {
+				return patternError(key.Token, "key %q not found in hash", key.Value)
+			}
+			if errObj := matchPattern(pattern.Values[i], pair.Value, binds, env); errObj != nil {
+				return errObj
+			}
+		}
+
+		return nil
+	}
+
+	return newErrorObj("unknown pattern: %T", pattern)
+}
+
+func patternError(tok token.Token, format string, a ...interface{}) *object.ErrorObj {
+	errObj := newErrorObj(format, a...)
+	errObj.Pos = tok.Pos
+	return errObj
+}
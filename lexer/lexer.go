@@ -3,42 +3,150 @@ package lexer
 // 字句解析とは、文字をToken構造体にすること
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/yuya-isaka/go-yuya-monkey/token"
 )
 
 type Lexer struct {
-	input string // ソースコード全部
-	pos   int    // 読んでいる場所
-	ch    byte   // 読んでいる場所のバイト
+	src      *bufio.Reader   // ソースコード（全部メモリに載せずに少しずつ読む）
+	ahead    []char          // peekで先読みした文字
+	done     bool            // ソースを読み終わった（EOFか読み込みエラー）
+	file     string          // ファイル名（位置情報用、なくてもいい）
+	pos      int             // 読んでいる場所（バイト単位）
+	ch       rune            // 読んでいる場所の文字
+	width    int             // chのバイト数（EOFなら0）
+	line     int             // 読んでいる場所の行
+	col      int             // 読んでいる場所の列
+	comments bool            // コメントをトークンとして返すかどうか
+	errors   []Error         // 字句解析のエラー
+	marking  bool            // markから読んだ文字をtextにためているか
+	text     strings.Builder // markから読んだ文字
+	interps  []interp        // 読んでいる途中の"${"（入れ子になるので積む）
+}
+
+// 文字列の中の${ }
+// 式の中の{ }を数えておいて、対応する}で文字列の続きに戻る
+type interp struct {
+	start  token.Position // 文字列の'"'の位置
+	braces int            // ${の中で開いている{の数
+}
+
+// 字句解析のエラー
+// ILLEGALトークンを返すときは必ずこれも残す（パーサーはILLEGALについては何も言わない）
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+// file:line:column: msg
+func (e Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// 先読みした文字（バイト数も覚えておかないとオフセットがずれる）
+type char struct {
+	ch    rune
+	width int
 }
 
-func NewLexer(input string) *Lexer {
-	return &Lexer{
-		input: input,
-		pos:   0,
-		ch:    input[0],
+// NewLexerに渡すオプション
+type Option func(*Lexer)
+
+// トークンの位置にファイル名をつける
+func WithFile(name string) Option {
+	return func(l *Lexer) {
+		l.file = name
+	}
+}
+
+// コメントを読み飛ばさずにtoken.COMMENTとして返す（フォーマッタやドキュメント生成用）
+func WithComments() Option {
+	return func(l *Lexer) {
+		l.comments = true
 	}
 }
 
+func NewLexer(input string, opts ...Option) *Lexer {
+	return NewReaderLexer(strings.NewReader(input), opts...)
+}
+
+// io.Readerから少しずつ読む（大きなファイルやパイプ用）
+// バッファはbufioの分と先読みの数文字だけ
+func NewReaderLexer(r io.Reader, opts ...Option) *Lexer {
+	l := &Lexer{
+		src:    bufio.NewReader(r),
+		pos:    0,
+		line:   1,
+		col:    1,
+		errors: []Error{},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	// 空のソースならいきなりEOF（chは0）
+	l.readChar()
+
+	return l
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
-	// 空白文字, 改行, タブ, キャリッジリターンを無視
-	for l.ch == '\n' || l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
-		l.nextPos()
+	// 空白文字, 改行, タブ, キャリッジリターン, コメントを無視
+	for {
+		for l.ch == '\n' || l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
+			l.nextPos()
+		}
+
+		if l.ch != '/' || (l.peek() != '/' && l.peek() != '*') {
+			break
+		}
+
+		start := l.position()
+		comment := l.readComment()
+
+		// 残す設定ならトークンとして返す
+		if l.comments {
+			tok = newToken(token.COMMENT, comment)
+			tok.Pos = start
+			return tok
+		}
 	}
 
+	// トークンの先頭の位置を覚えておく
+	start := l.position()
+
 	switch l.ch {
 	case '=':
-		if l.peek() == '=' {
+		switch l.peek() {
+		case '=':
 			ch := l.ch
 			l.nextPos()
 			tok = newToken(token.EQ, string(ch)+string(l.ch))
-		} else {
+		case '>':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.ARROW, string(ch)+string(l.ch))
+		default:
 			tok = newToken(token.ASSIGN, string(l.ch))
 		}
 	case '+':
-		tok = newToken(token.PLUS, string(l.ch))
+		if l.peek() == '=' {
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.PLUS_EQ, string(ch)+string(l.ch))
+		} else {
+			tok = newToken(token.PLUS, string(l.ch))
+		}
 	case ',':
 		tok = newToken(token.COMMA, string(l.ch))
 	case ';':
@@ -48,8 +156,19 @@ func (l *Lexer) NextToken() token.Token {
 	case ')':
 		tok = newToken(token.RPAREN, string(l.ch))
 	case '{':
+		if len(l.interps) > 0 {
+			l.interps[len(l.interps)-1].braces++
+		}
 		tok = newToken(token.LBRACE, string(l.ch))
 	case '}':
+		// ${ }の閉じかっこなら文字列の続きを読む
+		if n := len(l.interps); n > 0 {
+			if l.interps[n-1].braces == 0 {
+				tok = l.readStringPart(l.interps[n-1].start, false)
+				break
+			}
+			l.interps[n-1].braces--
+		}
 		tok = newToken(token.RBRACE, string(l.ch))
 	case '!':
 		if l.peek() == '=' {
@@ -60,94 +179,605 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, string(l.ch))
 		}
 	case '-':
-		tok = newToken(token.MINUS, string(l.ch))
+		if l.peek() == '=' {
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.MINUS_EQ, string(ch)+string(l.ch))
+		} else {
+			tok = newToken(token.MINUS, string(l.ch))
+		}
 	case '/':
-		tok = newToken(token.SLASH, string(l.ch))
+		if l.peek() == '=' {
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.DIV_EQ, string(ch)+string(l.ch))
+		} else {
+			tok = newToken(token.SLASH, string(l.ch))
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, string(l.ch))
+		switch l.peek() {
+		case '*':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.POWER, string(ch)+string(l.ch))
+		case '=':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.MUL_EQ, string(ch)+string(l.ch))
+		default:
+			tok = newToken(token.ASTERISK, string(l.ch))
+		}
 	case '<':
-		tok = newToken(token.LT, string(l.ch))
+		switch l.peek() {
+		case '=':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.LT_EQ, string(ch)+string(l.ch))
+		case '<':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.LSHIFT, string(ch)+string(l.ch))
+		default:
+			tok = newToken(token.LT, string(l.ch))
+		}
 	case '>':
-		tok = newToken(token.GT, string(l.ch))
+		switch l.peek() {
+		case '=':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.GT_EQ, string(ch)+string(l.ch))
+		case '>':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.RSHIFT, string(ch)+string(l.ch))
+		default:
+			tok = newToken(token.GT, string(l.ch))
+		}
+	case '%':
+		tok = newToken(token.PERCENT, string(l.ch))
+	case '&':
+		if l.peek() == '&' {
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.AND, string(ch)+string(l.ch))
+		} else {
+			tok = newToken(token.AMPERSAND, string(l.ch))
+		}
+	case '|':
+		switch l.peek() {
+		case '|':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.OR, string(ch)+string(l.ch))
+		case '>':
+			ch := l.ch
+			l.nextPos()
+			tok = newToken(token.PIPELINE, string(ch)+string(l.ch))
+		default:
+			tok = newToken(token.PIPE, string(l.ch))
+		}
+	case '^':
+		tok = newToken(token.CARET, string(l.ch))
+	case '~':
+		tok = newToken(token.TILDE, string(l.ch))
 	case 0: // EOF==0, 整数0==48
+		// ソースの途中のNUL文字はEOFじゃない（EOFは幅0）
+		if !l.eof() {
+			tok = newToken(token.ILLEGAL, string(l.ch))
+			l.addError(start, unexpected(l.ch, l.width))
+			break
+		}
+		// ${の中でソースが終わった
+		if n := len(l.interps); n > 0 {
+			l.addError(l.interps[n-1].start, "unterminated string literal")
+			l.interps = nil
+			tok = newToken(token.ILLEGAL, "")
+			break
+		}
 		tok = newToken(token.EOF, string(l.ch))
 	case '"':
-		pos := l.pos + 1
-		for {
-			l.nextPos()
-			// EOFで判断しないと"出るまで永遠に終わらない
-			// 「何かが出たら終わる」っていう条件分岐をするときは、対象のものが出ない時のことを考える
-			if l.ch == '"' || l.ch == 0 {
-				break
-			}
-		}
-		tok = newToken(token.STRING, l.input[pos:l.pos])
+		tok = l.readStringPart(start, true)
+	case '`':
+		tok = l.readRawString()
 	case '[':
 		tok = newToken(token.LBRACKET, string(l.ch))
 	case ']':
 		tok = newToken(token.RBRACKET, string(l.ch))
 	case ':':
 		tok = newToken(token.COLON, string(l.ch))
+	case '?':
+		tok = newToken(token.QUESTION, string(l.ch))
+	case '.':
+		// "..."か"."（".."は"."が2つ）
+		if l.peek() == '.' && l.peekN(2) == '.' {
+			l.nextPos()
+			l.nextPos()
+			tok = newToken(token.ELLIPSIS, "...")
+		} else {
+			tok = newToken(token.DOT, string(l.ch))
+		}
 	default:
 		switch {
 		case isLetter(l.ch):
-			pos := l.pos
+			l.mark()
 			// ーーじゃなかったら終わり系ははっきりしている
 			for isLetter(l.ch) {
 				l.nextPos()
 			}
+			name := l.marked()
 			// 次の文字まで進んでしまっているからここでリターン
 			// 文字だったら「キーワード」チェック。キーワードか変数かここじゃわからん
-			return newToken(token.LookKeyword(l.input[pos:l.pos]), l.input[pos:l.pos])
+			tok = newToken(token.LookKeyword(name), name)
+			tok.Pos = start
+			return tok
 		case isNumber(l.ch):
-			pos := l.pos
-			// ーーじゃなかったら終わり系ははっきりしている
-			for isNumber(l.ch) {
-				l.nextPos()
-			}
 			// 次の文字まで進んでしまっているからここでリターン
-			return newToken(token.INT, l.input[pos:l.pos])
+			tok = l.readNumber()
+			tok.Pos = start
+			return tok
 		default:
 			// おかしい
 			tok = newToken(token.ILLEGAL, string(l.ch))
+			l.addError(start, unexpected(l.ch, l.width))
 		}
 	}
 
 	l.nextPos()
 
+	tok.Pos = start
 	return tok
 }
 
+// エラーメッセージ（位置つきの文字列）
+func (l *Lexer) Errors() []string {
+	msgs := make([]string, 0, len(l.errors))
+	for _, e := range l.errors {
+		msgs = append(msgs, e.Error())
+	}
+	return msgs
+}
+
+// エラーを位置とメッセージに分けたまま返す
+func (l *Lexer) Diagnostics() []Error {
+	return l.errors
+}
+
 // -----------------------------------------------------------------
 
+// "//"か"/*"の位置で呼ばれて、コメントの次の文字まで進める
+// 返すのは区切り文字も含めたコメント全体
+func (l *Lexer) readComment() string {
+	start := l.position()
+	l.mark()
+
+	// 行コメントは改行の手前まで（改行は空白として読み飛ばす）
+	if l.peek() == '/' {
+		for l.ch != '\n' && !l.eof() {
+			l.nextPos()
+		}
+		return l.marked()
+	}
+
+	// ブロックコメントは"*/"まで
+	l.nextPos()
+	l.nextPos()
+	for {
+		// 閉じないままEOFに来たらエラー
+		if l.eof() {
+			l.addError(start, "unterminated block comment")
+			return l.marked()
+		}
+		if l.ch == '*' && l.peek() == '/' {
+			l.nextPos()
+			l.nextPos()
+			return l.marked()
+		}
+		l.nextPos()
+	}
+}
+
+// 数字の先頭で呼ばれて、数字の次の文字まで進める
+// 小数点か指数があったらFLOAT（3.14, 1e-9, 2.5E3）
+// 0x1F, 0o17, 0b1010 みたいな基数つきと、1_000_000 みたいな区切りも読む
+// 書き方がおかしい数字はILLEGAL（値が大きすぎるのはパーサーで見る）
+func (l *Lexer) readNumber() token.Token {
+	start := l.position()
+	l.mark()
+	tt := token.TokenType(token.INT)
+
+	// 基数つきは英数字を全部読んでしまう（0b102みたいな間違いを1つのトークンにするため）
+	if l.ch == '0' && isBasePrefix(l.peek()) {
+		l.nextPos()
+		l.nextPos()
+		for isLetter(l.ch) || isNumber(l.ch) {
+			l.nextPos()
+		}
+		return l.checkNumber(start, tt, l.marked())
+	}
+
+	// ーーじゃなかったら終わり系ははっきりしている
+	for isNumber(l.ch) || l.ch == '_' {
+		l.nextPos()
+	}
+
+	// "1."や"1.foo"は小数じゃない。"."の次が数字のときだけ
+	if l.ch == '.' && isNumber(l.peek()) {
+		tt = token.FLOAT
+		l.nextPos()
+		for isNumber(l.ch) || l.ch == '_' {
+			l.nextPos()
+		}
+	}
+
+	// 指数は"e"の後に（符号と）数字が続くときだけ
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peek()
+		if isNumber(next) || ((next == '+' || next == '-') && isNumber(l.peekN(2))) {
+			tt = token.FLOAT
+			l.nextPos()
+			l.nextPos()
+			// "_"も読んでおいて、正しい位置かはcheckNumberで見る（仮数と同じ）
+			for isNumber(l.ch) || l.ch == '_' {
+				l.nextPos()
+			}
+		}
+	}
+
+	// 続く英数字も読んでしまう（123abc, 1.5e を分けずに1つの間違いにするため）
+	for isLetter(l.ch) || isNumber(l.ch) {
+		l.nextPos()
+	}
+
+	return l.checkNumber(start, tt, l.marked())
+}
+
+// 数字の書き方をチェックして、ダメならエラーを残してILLEGALにする
+func (l *Lexer) checkNumber(start token.Position, tt token.TokenType, name string) token.Token {
+	if msg := numberError(name); msg != "" {
+		l.addError(start, msg)
+		return newToken(token.ILLEGAL, name)
+	}
+	return newToken(tt, name)
+}
+
+// 数字の書き方のおかしいところ（なければ""）
+func numberError(name string) string {
+	digits := name
+	isDigit := isNumber
+	if len(name) > 1 && name[0] == '0' && isBasePrefix(rune(name[1])) {
+		var kind string
+		switch name[1] {
+		case 'x', 'X':
+			kind, isDigit = "hexadecimal", isHexNumber
+		case 'o', 'O':
+			kind, isDigit = "octal", func(ch rune) bool { return '0' <= ch && ch <= '7' }
+		case 'b', 'B':
+			kind, isDigit = "binary", func(ch rune) bool { return ch == '0' || ch == '1' }
+		}
+
+		// 0x_1F はOK（接頭辞の直後の"_"は区切りとして使える）
+		digits = name[2:]
+		if strings.Trim(digits, "_") == "" {
+			return fmt.Sprintf("%s literal has no digits", kind)
+		}
+		for _, ch := range digits {
+			if ch != '_' && !isDigit(ch) {
+				return fmt.Sprintf("invalid digit %q in %s literal", ch, kind)
+			}
+		}
+		digits = "0" + digits
+	} else if msg := decimalError(name); msg != "" {
+		return msg
+	}
+
+	// "_"は数字と数字の間だけ（1__0, _1, 1_, 1_.5 はダメ）
+	for i, ch := range digits {
+		if ch != '_' {
+			continue
+		}
+		if i == 0 || i == len(digits)-1 || !isDigit(rune(digits[i-1])) || !isDigit(rune(digits[i+1])) {
+			return "'_' must separate successive digits"
+		}
+	}
+
+	return ""
+}
+
+// 10進数の形のおかしいところ（なければ""）
+// 数字 [. 数字] [e [+-] 数字] の形だけOK（"_"の位置はnumberErrorで見る）
+func decimalError(name string) string {
+	const digits = "0123456789_"
+
+	rest := strings.TrimLeft(name, digits)
+	if strings.HasPrefix(rest, ".") {
+		rest = strings.TrimLeft(rest[1:], digits)
+	}
+
+	if strings.HasPrefix(rest, "e") || strings.HasPrefix(rest, "E") {
+		exp := strings.TrimLeft(rest[1:], "+-")
+		rest = strings.TrimLeft(exp, digits)
+		if len(rest) == len(exp) {
+			return "exponent has no digits"
+		}
+	}
+
+	if rest != "" {
+		ch, _ := utf8.DecodeRuneInString(rest)
+		return fmt.Sprintf("invalid digit %q in decimal literal", ch)
+	}
+
+	return ""
+}
+
+// 文字列の'"'か、${ }の'}'の位置で呼ばれて、閉じる'"'か次の"${"の'{'の位置で終わる
+// エスケープシーケンスはここで実際の文字に直す
+//
+//	"a ${x} b ${y} c" → INTERP_START(a ) x INTERP_MID( b ) y INTERP_END( c)
+//	"abc"             → STRING(abc)
+func (l *Lexer) readStringPart(start token.Position, first bool) token.Token {
+	var out strings.Builder
+
+	for {
+		l.nextPos()
+
+		// EOFで判断しないと"出るまで永遠に終わらない
+		// 「何かが出たら終わる」っていう条件分岐をするときは、対象のものが出ない時のことを考える
+		// 外側の${も全部閉じていないけど、エラーはこの1つだけにする
+		if l.eof() {
+			l.addError(start, "unterminated string literal")
+			l.interps = nil
+			return newToken(token.ILLEGAL, out.String())
+		}
+
+		switch l.ch {
+		case '"':
+			if first {
+				return newToken(token.STRING, out.String())
+			}
+			l.interps = l.interps[:len(l.interps)-1]
+			return newToken(token.INTERP_END, out.String())
+
+		case '$':
+			if l.peek() != '{' {
+				out.WriteRune(l.ch)
+				continue
+			}
+			l.nextPos()
+
+			if first {
+				l.interps = append(l.interps, interp{start: start})
+				return newToken(token.INTERP_START, out.String())
+			}
+			return newToken(token.INTERP_MID, out.String())
+
+		case '\\':
+			l.readEscape(&out)
+
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// '\\'の位置で呼ばれて、エスケープシーケンスの最後の文字で終わる
+func (l *Lexer) readEscape(out *strings.Builder) {
+	pos := l.position()
+	l.nextPos()
+
+	// 閉じていない文字列のエラーはreadStringPartに任せる
+	if l.eof() {
+		return
+	}
+
+	switch l.ch {
+	case 'n':
+		out.WriteRune('\n')
+	case 't':
+		out.WriteRune('\t')
+	case 'r':
+		out.WriteRune('\r')
+	case '0':
+		out.WriteRune(0)
+	case '\\', '"', '$':
+		out.WriteRune(l.ch)
+
+	// \u{1F600} みたいにコードポイントを16進で書く
+	case 'u':
+		if l.peek() != '{' {
+			l.addError(pos, "invalid unicode escape: missing '{'")
+			return
+		}
+		l.nextPos()
+
+		var digits strings.Builder
+		for l.peek() != '}' && l.peek() != '"' && l.peek() != 0 {
+			l.nextPos()
+			digits.WriteRune(l.ch)
+		}
+		if l.peek() != '}' {
+			l.addError(pos, "invalid unicode escape: missing '}'")
+			return
+		}
+		l.nextPos()
+
+		code, err := strconv.ParseUint(digits.String(), 16, 32)
+		if err != nil || digits.Len() > 6 || !utf8.ValidRune(rune(code)) {
+			l.addError(pos, fmt.Sprintf("invalid unicode escape \\u{%s}", digits.String()))
+			return
+		}
+		out.WriteRune(rune(code))
+
+	default:
+		l.addError(pos, fmt.Sprintf("unknown escape sequence \\%c", l.ch))
+		out.WriteRune(l.ch)
+	}
+}
+
+// '`'の位置で呼ばれて、閉じる'`'の位置で終わる
+// エスケープはしないし、改行もそのまま入る
+func (l *Lexer) readRawString() token.Token {
+	start := l.position()
+	l.nextPos()
+	l.mark()
+
+	for {
+		if l.eof() {
+			l.addError(start, "unterminated raw string literal")
+			return newToken(token.ILLEGAL, l.marked())
+		}
+
+		if l.ch == '`' {
+			return newToken(token.STRING, l.marked())
+		}
+
+		l.nextPos()
+	}
+}
+
+// エラーを位置つきでためる
+func (l *Lexer) addError(pos token.Position, msg string) {
+	l.errors = append(l.errors, Error{Pos: pos, Msg: msg})
+}
+
+// 知らない文字のエラーメッセージ（見えない文字もあるのでコードポイントもつける）
+// 壊れたUTF-8はutf8.RuneError（幅1）で来る
+func unexpected(ch rune, width int) string {
+	if ch == utf8.RuneError && width == 1 {
+		return "invalid UTF-8 encoding"
+	}
+	return fmt.Sprintf("unexpected character %q (U+%04X)", ch, ch)
+}
+
 func newToken(tt token.TokenType, name string) token.Token {
 	return token.Token{Type: tt, Name: name}
 }
 
 func (l *Lexer) nextPos() {
-	peekPos := l.pos + 1
-	if peekPos >= len(l.input) {
-		l.ch = 0
+	// EOFから先には進まない（位置もずれない）
+	if l.width == 0 {
+		return
+	}
+
+	// トークンの文字列をためている途中ならためる
+	if l.marking {
+		l.text.WriteRune(l.ch)
+	}
+
+	// 改行を抜けたら次の行
+	if l.ch == '\n' {
+		l.line += 1
+		l.col = 1
 	} else {
-		l.ch = l.input[peekPos]
+		l.col += 1
 	}
 
-	l.pos += 1
+	l.pos += l.width
+	l.readChar()
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// 次の文字をUTF-8として読む（先読みしてあればそっちから）
+// 列は文字単位、オフセットはバイト単位
+func (l *Lexer) readChar() {
+	if len(l.ahead) > 0 {
+		l.ch, l.width = l.ahead[0].ch, l.ahead[0].width
+		l.ahead = l.ahead[1:]
+		return
+	}
+
+	c := l.read()
+	l.ch, l.width = c.ch, c.width
+}
+
+// ソースから1文字読む。EOFなら0（幅0）
+// 壊れたUTF-8はutf8.RuneError（幅1）になるので、そのままILLEGALになる
+func (l *Lexer) read() char {
+	// 一度終わったらもう読まない（エラーを何回も出さないため）
+	if l.done {
+		return char{}
+	}
+
+	ch, width, err := l.src.ReadRune()
+	if err != nil {
+		l.done = true
+		if err != io.EOF {
+			l.addError(l.position(), fmt.Sprintf("read error: %s", err))
+		}
+		return char{}
+	}
+	return char{ch: ch, width: width}
+}
+
+// ここからの文字をためはじめる
+func (l *Lexer) mark() {
+	l.text.Reset()
+	l.marking = true
+}
+
+// markからここまで（chの手前まで）の文字
+func (l *Lexer) marked() string {
+	l.marking = false
+	return l.text.String()
+}
+
+// 今読んでいる場所の位置
+func (l *Lexer) position() token.Position {
+	return token.Position{
+		File:   l.file,
+		Line:   l.line,
+		Column: l.col,
+		Offset: l.pos,
+	}
+}
+
+// Unicodeの文字なら識別子に使える（名前, café, ...）
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isNumber(ch byte) bool {
+func isNumber(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func (l Lexer) peek() byte {
-	peekPos := l.pos + 1
+func isHexNumber(ch rune) bool {
+	return isNumber(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+// 0x, 0o, 0b の2文字目
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// ソースを読み終わった（chが0でもNUL文字のことがあるので幅で見る）
+func (l *Lexer) eof() bool {
+	return l.width == 0
+}
+
+func (l *Lexer) peek() rune {
+	return l.peekN(1)
+}
+
+// n文字先を見る（1なら次の文字）
+// 読んだ文字はaheadにとっておいて、readCharで使う
+func (l *Lexer) peekN(n int) rune {
 	// 先を見るときは境界チェックを気をつけて
-	if peekPos >= len(l.input) {
+	if l.width == 0 {
+		return 0
+	}
+
+	for len(l.ahead) < n {
+		c := l.read()
+		l.ahead = append(l.ahead, c)
+		if c.width == 0 {
+			break
+		}
+	}
+
+	if len(l.ahead) < n {
 		return 0
 	}
-	return l.input[peekPos]
+	return l.ahead[n-1].ch
 }
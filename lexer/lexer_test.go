@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/yuya-isaka/go-yuya-monkey/token"
 )
@@ -15,7 +19,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -141,3 +145,628 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestNextTokenPosition(t *testing.T) {
+	input := `let x = 5;
+  x + 10;
+"ab" == y`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{File: "script.mk", Line: 1, Column: 1, Offset: 0}},
+		{token.IDENT, token.Position{File: "script.mk", Line: 1, Column: 5, Offset: 4}},
+		{token.ASSIGN, token.Position{File: "script.mk", Line: 1, Column: 7, Offset: 6}},
+		{token.INT, token.Position{File: "script.mk", Line: 1, Column: 9, Offset: 8}},
+		{token.SEMICOLON, token.Position{File: "script.mk", Line: 1, Column: 10, Offset: 9}},
+		{token.IDENT, token.Position{File: "script.mk", Line: 2, Column: 3, Offset: 13}},
+		{token.PLUS, token.Position{File: "script.mk", Line: 2, Column: 5, Offset: 15}},
+		{token.INT, token.Position{File: "script.mk", Line: 2, Column: 7, Offset: 17}},
+		{token.SEMICOLON, token.Position{File: "script.mk", Line: 2, Column: 9, Offset: 19}},
+		{token.STRING, token.Position{File: "script.mk", Line: 3, Column: 1, Offset: 21}},
+		{token.EQ, token.Position{File: "script.mk", Line: 3, Column: 6, Offset: 26}},
+		{token.IDENT, token.Position{File: "script.mk", Line: 3, Column: 9, Offset: 29}},
+		{token.EOF, token.Position{File: "script.mk", Line: 3, Column: 10, Offset: 30}},
+	}
+
+	l := NewLexer(input, WithFile("script.mk"))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// 一行コメント
+let x = 5; // 後ろにも
+/* ブロック
+   コメント */ x / 2;
+/**/x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, "\x00"},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Name != tt.expectedContent {
+			t.Fatalf("tests[%d] - Content wrong. expected=%q, got=%q",
+				i, tt.expectedContent, tok.Name)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Fatalf("unexpected lexer errors: %v", l.Errors())
+	}
+}
+
+func TestCommentTokens(t *testing.T) {
+	input := `// head
+x /* mid */ y`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+		expectedLine    int
+	}{
+		{token.COMMENT, "// head", 1},
+		{token.IDENT, "x", 2},
+		{token.COMMENT, "/* mid */", 2},
+		{token.IDENT, "y", 2},
+		{token.EOF, "\x00", 2},
+	}
+
+	l := NewLexer(input, WithComments())
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Name != tt.expectedContent {
+			t.Fatalf("tests[%d] - Content wrong. expected=%q, got=%q",
+				i, tt.expectedContent, tok.Name)
+		}
+
+		if tok.Pos.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d",
+				i, tt.expectedLine, tok.Pos.Line)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := NewLexer("x /* never closed")
+
+	if tok := l.NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("expected IDENT. got=%q", tok.Type)
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF. got=%q", tok.Type)
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error. got=%d (%v)", len(errors), errors)
+	}
+	if errors[0] != "1:3: unterminated block comment" {
+		t.Errorf("wrong error. got=%q", errors[0])
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := `let 名前 = "日本";
+café + 名前`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+		expectedPos     token.Position
+	}{
+		{token.LET, "let", token.Position{Line: 1, Column: 1, Offset: 0}},
+		{token.IDENT, "名前", token.Position{Line: 1, Column: 5, Offset: 4}},
+		{token.ASSIGN, "=", token.Position{Line: 1, Column: 8, Offset: 11}},
+		{token.STRING, "日本", token.Position{Line: 1, Column: 10, Offset: 13}},
+		{token.SEMICOLON, ";", token.Position{Line: 1, Column: 14, Offset: 21}},
+		{token.IDENT, "café", token.Position{Line: 2, Column: 1, Offset: 23}},
+		{token.PLUS, "+", token.Position{Line: 2, Column: 6, Offset: 29}},
+		{token.IDENT, "名前", token.Position{Line: 2, Column: 8, Offset: 31}},
+		{token.EOF, "\x00", token.Position{Line: 2, Column: 10, Offset: 37}},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Name != tt.expectedContent {
+			t.Fatalf("tests[%d] - Content wrong. expected=%q, got=%q",
+				i, tt.expectedContent, tok.Name)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectType  token.TokenType
+		expectValue string
+		expectError string
+	}{
+		{`"a\nb"`, token.STRING, "a\nb", ""},
+		{`"tab\there"`, token.STRING, "tab\there", ""},
+		{`"\"quoted\""`, token.STRING, `"quoted"`, ""},
+		{`"back\\slash"`, token.STRING, `back\slash`, ""},
+		{`"\u{65E5}\u{672C}"`, token.STRING, "日本", ""},
+		{`"\u{1F600}"`, token.STRING, "😀", ""},
+		{`"\${x} costs $5"`, token.STRING, "${x} costs $5", ""},
+		{"`raw ${x}`", token.STRING, "raw ${x}", ""},
+		{`"\q"`, token.STRING, "q", `1:2: unknown escape sequence \q`},
+		{`"\u{110000}"`, token.STRING, "", `1:2: invalid unicode escape \u{110000}`},
+		{`"\u41"`, token.STRING, "41", `1:2: invalid unicode escape: missing '{'`},
+		{"`raw\\n\nline`", token.STRING, "raw\\n\nline", ""},
+		{"\"a\x00b\"", token.STRING, "a\x00b", ""},
+		{"`a\x00b`", token.STRING, "a\x00b", ""},
+		{`"never closed`, token.ILLEGAL, "never closed", "1:1: unterminated string literal"},
+		{"`never closed", token.ILLEGAL, "never closed", "1:1: unterminated raw string literal"},
+	}
+
+	for i, tt := range tests {
+		l := NewLexer(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectType {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectType, tok.Type)
+		}
+
+		if tok.Name != tt.expectValue {
+			t.Errorf("tests[%d] - value wrong. expected=%q, got=%q", i, tt.expectValue, tok.Name)
+		}
+
+		errors := l.Errors()
+		if tt.expectError == "" {
+			if len(errors) != 0 {
+				t.Errorf("tests[%d] - unexpected errors: %v", i, errors)
+			}
+			continue
+		}
+
+		if len(errors) != 1 || errors[0] != tt.expectError {
+			t.Errorf("tests[%d] - wrong errors. expected=%q, got=%v", i, tt.expectError, errors)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"a ${x} b ${ {"k": "${y}"}["k"] }" + ""`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+		expectedPos     token.Position
+	}{
+		{token.INTERP_START, "a ", token.Position{Line: 1, Column: 1, Offset: 0}},
+		{token.IDENT, "x", token.Position{Line: 1, Column: 6, Offset: 5}},
+		{token.INTERP_MID, " b ", token.Position{Line: 1, Column: 7, Offset: 6}},
+		{token.LBRACE, "{", token.Position{Line: 1, Column: 14, Offset: 13}},
+		{token.STRING, "k", token.Position{Line: 1, Column: 15, Offset: 14}},
+		{token.COLON, ":", token.Position{Line: 1, Column: 18, Offset: 17}},
+		{token.INTERP_START, "", token.Position{Line: 1, Column: 20, Offset: 19}},
+		{token.IDENT, "y", token.Position{Line: 1, Column: 23, Offset: 22}},
+		{token.INTERP_END, "", token.Position{Line: 1, Column: 24, Offset: 23}},
+		{token.RBRACE, "}", token.Position{Line: 1, Column: 26, Offset: 25}},
+		{token.LBRACKET, "[", token.Position{Line: 1, Column: 27, Offset: 26}},
+		{token.STRING, "k", token.Position{Line: 1, Column: 28, Offset: 27}},
+		{token.RBRACKET, "]", token.Position{Line: 1, Column: 31, Offset: 30}},
+		{token.INTERP_END, "", token.Position{Line: 1, Column: 33, Offset: 32}},
+		{token.PLUS, "+", token.Position{Line: 1, Column: 36, Offset: 35}},
+		{token.STRING, "", token.Position{Line: 1, Column: 38, Offset: 37}},
+		{token.EOF, "\x00", token.Position{Line: 1, Column: 40, Offset: 39}},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Name != tt.expectedContent || tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q at %+v, got=%s %q at %+v",
+				i, tt.expectedType, tt.expectedContent, tt.expectedPos, tok.Type, tok.Name, tok.Pos)
+		}
+	}
+
+	if errors := l.Errors(); len(errors) != 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+
+	// ${の中でソースが終わる
+	l = NewLexer(`"a ${b`)
+	for _, expected := range []token.TokenType{token.INTERP_START, token.IDENT, token.ILLEGAL, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+
+	if errors := l.Errors(); len(errors) != 1 || errors[0] != "1:1: unterminated string literal" {
+		t.Errorf("wrong errors. got=%v", errors)
+	}
+
+	// ${の中の文字列が閉じないまま終わる（エラーは1つだけ）
+	l = NewLexer(`"a ${"`)
+	for _, expected := range []token.TokenType{token.INTERP_START, token.ILLEGAL, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+
+	if errors := l.Errors(); len(errors) != 1 || errors[0] != "1:6: unterminated string literal" {
+		t.Errorf("wrong errors. got=%v", errors)
+	}
+
+	// ${ }の後ろで終わる
+	l = NewLexer(`"a ${b} c`)
+	for _, expected := range []token.TokenType{token.INTERP_START, token.IDENT, token.ILLEGAL, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+
+	if errors := l.Errors(); len(errors) != 1 || errors[0] != "1:1: unterminated string literal" {
+		t.Errorf("wrong errors. got=%v", errors)
+	}
+}
+
+func TestRawStringPosition(t *testing.T) {
+	l := NewLexer("`a\nb` x")
+
+	if tok := l.NextToken(); tok.Type != token.STRING {
+		t.Fatalf("expected STRING. got=%q", tok.Type)
+	}
+
+	tok := l.NextToken()
+	expect := token.Position{Line: 2, Column: 4, Offset: 6}
+	if tok.Pos != expect {
+		t.Fatalf("position wrong. expected=%+v, got=%+v", expect, tok.Pos)
+	}
+}
+
+func TestFloat(t *testing.T) {
+	input := `3.14 1e-9 2.5E3 10e+2 7 1.foo 3e x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+	}{
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E3"},
+		{token.FLOAT, "10e+2"},
+		{token.INT, "7"},
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENT, "foo"},
+		{token.ILLEGAL, "3e"},
+		{token.IDENT, "x"},
+		{token.EOF, "\x00"},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Name != tt.expectedContent {
+			t.Fatalf("tests[%d] - Content wrong. expected=%q, got=%q",
+				i, tt.expectedContent, tok.Name)
+		}
+	}
+}
+
+func TestIntegerForms(t *testing.T) {
+	input := `0x1F 0o17 0b1010 1_000_000 0b102 0xZZ;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+	}{
+		{token.INT, "0x1F"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.ILLEGAL, "0b102"},
+		{token.ILLEGAL, "0xZZ"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, "\x00"},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Name != tt.expectedContent {
+			t.Fatalf("tests[%d] - Content wrong. expected=%q, got=%q",
+				i, tt.expectedContent, tok.Name)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `<= >= % ** & | ^ ~ << >> < > * = += -= *= /= - / && || &&& ... => ==> |> ||> . .. ?`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedContent string
+	}{
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.AMPERSAND, "&"},
+		{token.PIPE, "|"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.LSHIFT, "<<"},
+		{token.RSHIFT, ">>"},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.ASTERISK, "*"},
+		{token.ASSIGN, "="},
+		{token.PLUS_EQ, "+="},
+		{token.MINUS_EQ, "-="},
+		{token.MUL_EQ, "*="},
+		{token.DIV_EQ, "/="},
+		{token.MINUS, "-"},
+		{token.SLASH, "/"},
+		{token.AND, "&&"},
+		{token.OR, "||"},
+		{token.AND, "&&"},
+		{token.AMPERSAND, "&"},
+		{token.ELLIPSIS, "..."},
+		{token.ARROW, "=>"},
+		{token.EQ, "=="},
+		{token.GT, ">"},
+		{token.PIPELINE, "|>"},
+		{token.OR, "||"},
+		{token.GT, ">"},
+		{token.DOT, "."},
+		{token.DOT, "."},
+		{token.DOT, "."},
+		{token.QUESTION, "?"},
+		{token.EOF, "\x00"},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Name != tt.expectedContent {
+			t.Fatalf("tests[%d] - Content wrong. expected=%q, got=%q",
+				i, tt.expectedContent, tok.Name)
+		}
+	}
+}
+
+func TestEmptyInput(t *testing.T) {
+	inputs := []string{"", " ", "\n\t", "// only comment"}
+
+	for _, input := range inputs {
+		l := NewLexer(input)
+
+		// 何回呼んでもEOFのまま
+		for i := 0; i < 2; i++ {
+			tok := l.NextToken()
+			if tok.Type != token.EOF {
+				t.Fatalf("input %q - expected EOF. got=%q", input, tok.Type)
+			}
+		}
+	}
+}
+
+func TestReaderLexer(t *testing.T) {
+	input := "let 名前 = `a\nb`; // c\n1e-9 /* x */ 0x1F"
+
+	// 1バイトずつしか返さないReaderでも、文字列から読んだときと同じトークンになる
+	expect := NewLexer(input)
+	l := NewReaderLexer(iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; ; i++ {
+		want := expect.NextToken()
+		got := l.NextToken()
+
+		if got != want {
+			t.Fatalf("tokens[%d] - wrong. expected=%+v, got=%+v", i, want, got)
+		}
+
+		if want.Type == token.EOF {
+			break
+		}
+	}
+}
+
+func TestReaderLexerLargeInput(t *testing.T) {
+	const n = 100000
+
+	// 全部を文字列にしないで、パイプで流し込む
+	r, w := io.Pipe()
+	go func() {
+		for i := 0; i < n; i++ {
+			io.WriteString(w, "let x = 1;\n")
+		}
+		w.Close()
+	}()
+
+	l := NewReaderLexer(r, WithFile("gen.mk"))
+
+	count := 0
+	var last token.Token
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		count++
+		last = tok
+	}
+
+	if count != n*5 {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d", n*5, count)
+	}
+
+	expect := token.Position{File: "gen.mk", Line: n, Column: 10, Offset: (n-1)*11 + 9}
+	if last.Pos != expect {
+		t.Fatalf("last position wrong. expected=%+v, got=%+v", expect, last.Pos)
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("x "), iotest.ErrReader(errors.New("boom")))
+	l := NewReaderLexer(r)
+
+	if tok := l.NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("expected IDENT. got=%q", tok.Type)
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF. got=%q", tok.Type)
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 || errors[0] != "1:3: read error: boom" {
+		t.Fatalf("wrong errors. got=%v", errors)
+	}
+}
+
+func TestIllegalDiagnostics(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType token.TokenType
+		expectedName string
+		expectedPos  token.Position
+		expectedMsg  string
+	}{
+		{"x # y", token.ILLEGAL, "#", token.Position{Line: 1, Column: 3, Offset: 2}, "unexpected character '#' (U+0023)"},
+		{"\n  @", token.ILLEGAL, "@", token.Position{Line: 2, Column: 3, Offset: 3}, "unexpected character '@' (U+0040)"},
+		{"a\xffb", token.ILLEGAL, "�", token.Position{Line: 1, Column: 2, Offset: 1}, "invalid UTF-8 encoding"},
+		{"let a = 1;\x00 @", token.ILLEGAL, "\x00", token.Position{Line: 1, Column: 11, Offset: 10}, "unexpected character '\\x00' (U+0000)"},
+		{`let s = "abc`, token.ILLEGAL, "abc", token.Position{Line: 1, Column: 9, Offset: 8}, "unterminated string literal"},
+		{"0b102", token.ILLEGAL, "0b102", token.Position{Line: 1, Column: 1, Offset: 0}, "invalid digit '2' in binary literal"},
+		{"0xG", token.ILLEGAL, "0xG", token.Position{Line: 1, Column: 1, Offset: 0}, "invalid digit 'G' in hexadecimal literal"},
+		{"0o", token.ILLEGAL, "0o", token.Position{Line: 1, Column: 1, Offset: 0}, "octal literal has no digits"},
+		{"1 + 1__0", token.ILLEGAL, "1__0", token.Position{Line: 1, Column: 5, Offset: 4}, "'_' must separate successive digits"},
+		{"2.5_", token.ILLEGAL, "2.5_", token.Position{Line: 1, Column: 1, Offset: 0}, "'_' must separate successive digits"},
+		{"1e1_", token.ILLEGAL, "1e1_", token.Position{Line: 1, Column: 1, Offset: 0}, "'_' must separate successive digits"},
+		{"1_0e1__0", token.ILLEGAL, "1_0e1__0", token.Position{Line: 1, Column: 1, Offset: 0}, "'_' must separate successive digits"},
+		{"2.5e-1_", token.ILLEGAL, "2.5e-1_", token.Position{Line: 1, Column: 1, Offset: 0}, "'_' must separate successive digits"},
+		{"1e_5", token.ILLEGAL, "1e_5", token.Position{Line: 1, Column: 1, Offset: 0}, "'_' must separate successive digits"},
+		{"x = 1.5e;", token.ILLEGAL, "1.5e", token.Position{Line: 1, Column: 5, Offset: 4}, "exponent has no digits"},
+		{"1e+", token.ILLEGAL, "1e", token.Position{Line: 1, Column: 1, Offset: 0}, "exponent has no digits"},
+		{"123abc", token.ILLEGAL, "123abc", token.Position{Line: 1, Column: 1, Offset: 0}, "invalid digit 'a' in decimal literal"},
+		{"3.14e2x", token.ILLEGAL, "3.14e2x", token.Position{Line: 1, Column: 1, Offset: 0}, "invalid digit 'x' in decimal literal"},
+	}
+
+	for _, tt := range tests {
+		l := NewLexer(tt.input)
+
+		var illegal *token.Token
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				illegal = &tok
+				break
+			}
+		}
+
+		if illegal == nil {
+			t.Errorf("%q - no ILLEGAL token", tt.input)
+			continue
+		}
+
+		if illegal.Name != tt.expectedName || illegal.Pos != tt.expectedPos {
+			t.Errorf("%q - wrong token. expected=%q at %v, got=%q at %v", tt.input, tt.expectedName, tt.expectedPos, illegal.Name, illegal.Pos)
+		}
+
+		diags := l.Diagnostics()
+		if len(diags) != 1 {
+			t.Errorf("%q - expected 1 diagnostic. got=%v", tt.input, diags)
+			continue
+		}
+
+		if diags[0].Pos != tt.expectedPos || diags[0].Msg != tt.expectedMsg {
+			t.Errorf("%q - wrong diagnostic. expected=%q at %v, got=%q at %v", tt.input, tt.expectedMsg, tt.expectedPos, diags[0].Msg, diags[0].Pos)
+		}
+	}
+}
+
+func TestValidNumbersHaveNoDiagnostics(t *testing.T) {
+	l := NewLexer("0x_1F 0xdead_BEEF 0o7_7 0b1_0 1_000 3.141_592 1e1_0 1_0e1_0 0")
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			t.Errorf("unexpected ILLEGAL %q", tok.Name)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected lexer errors: %v", l.Errors())
+	}
+}
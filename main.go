@@ -9,6 +9,11 @@ import (
 )
 
 func main() {
+	// サブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "tokens" {
+		os.Exit(runTokens(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// getpwnam_r() と getpwuid_r() 関 数 は 、 そ れ ぞ れ getpwnam() と getpwuid() と 同 じ 情 報 を 取 得 す る が 、 取 得 し た passwd 構 造 体 を pwd が 指 す 領 域 に 格 納 す る 。 passwd 構 造 体 の メ ン バ ー が 指 す 文 字 列 は 、 サ イ ズ buflen の バ ッ フ ァ ー buf に 格 納 さ れ る 。 成 功 し た 場 合 *result に は 結 果 へ の ポ イ ン タ ー が 格 納 さ れ る 。 エ ン ト リ ー が 見 つ か ら な か っ た 場 合 や エ ラ ー が 発 生 し た 場 合 に は *result に は NULL が 入 る 。 呼 び 出 し
 	// Current()
 	// current()
@@ -31,3 +31,19 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// 再代入（x = 1）
+// Setと違って、その変数を定義した環境（内側から外側に探して最初に見つかったところ）を書き換える
+// どこにもなければfalse
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return val, true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+
+	return nil, false
+}
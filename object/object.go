@@ -4,16 +4,22 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/yuya-isaka/go-yuya-monkey/ast"
+	"github.com/yuya-isaka/go-yuya-monkey/token"
 )
 
 const (
 	NULL     = "NULL"
 	INT      = "INT"
+	FLOAT    = "FLOAT"
 	BOOL     = "BOOL"
 	RETURN   = "RETURN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 	ERROR    = "ERROR"
 	FUNCTION = "FUNCTION"
 	STRING   = "STRING"
@@ -45,6 +51,23 @@ func (i IntObj) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
 // ---------------------------------
 
+type FloatObj struct {
+	Value float64
+}
+
+func (f FloatObj) Type() ObjectType { return FLOAT }
+
+// 整数と見分けがつくように、小数点がなければ".0"をつける（3 → 3.0）
+func (f FloatObj) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// ---------------------------------
+
 type BoolObj struct {
 	Value bool
 }
@@ -70,12 +93,36 @@ func (r ReturnObj) Inspect() string  { return r.Value.Inspect() }
 
 // ---------------------------------
 
+// breakとcontinueもReturnObjと同じように、ループまでブロックを抜けていく
+// ループの外まで来たらエラーにするので位置を持っておく
+type BreakObj struct {
+	Pos token.Position
+}
+
+func (b BreakObj) Type() ObjectType { return BREAK }
+func (b BreakObj) Inspect() string  { return "break" }
+
+type ContinueObj struct {
+	Pos token.Position
+}
+
+func (c ContinueObj) Type() ObjectType { return CONTINUE }
+func (c ContinueObj) Inspect() string  { return "continue" }
+
+// ---------------------------------
+
 type ErrorObj struct {
 	Value string
+	Pos   token.Position // エラーが起きた場所（わからなければゼロ値）
 }
 
 func (e ErrorObj) Type() ObjectType { return ERROR }
-func (e ErrorObj) Inspect() string  { return "ERROR: " + e.Value }
+func (e ErrorObj) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Value
+	}
+	return "ERROR: " + e.Value
+}
 
 // ---------------------------------
 
@@ -86,6 +133,8 @@ func (e ErrorObj) Inspect() string  { return "ERROR: " + e.Value }
 // Callの時に評価したいから、そのままノードを持っておかないといけない
 type FunctionObj struct {
 	Parameters []*ast.IdentNode
+	Defaults   []ast.Expression // デフォルト値（呼ぶたびに評価する）
+	Rest       *ast.IdentNode   // 残りの引数を配列で受け取る（なければnil）
 	Body       *ast.BlockNode
 	Env        *Environment // クロージャだ
 }
@@ -96,17 +145,9 @@ func (f FunctionObj) Type() ObjectType { return FUNCTION }
 func (f FunctionObj) Inspect() string {
 	var out bytes.Buffer
 
-	// 『var params []string』でも同じ
-	// なぜなら、今回はappendで追加しているから
-	// 他の使い方をするなら注意。
-	params := make([]string, len(f.Parameters))
-	for i, p := range f.Parameters {
-		params[i] = p.String()
-	}
-
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.FormatParameters(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n  ")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n")
@@ -178,6 +219,15 @@ func (i *IntObj) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// 1.0 == 1 なので、整数になる小数は同じ値の整数と同じキーにする（-0.0も0になる）
+// それ以外はビット列をそのままキーにする
+func (f *FloatObj) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return (&IntObj{Value: int64(f.Value)}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *StringObj) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &StringObj{Value: "Hello World"}
@@ -58,3 +61,89 @@ func TestIntegerHashKey(t *testing.T) {
 		t.Errorf("integers with twoerent content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	half1 := &FloatObj{Value: 0.5}
+	half2 := &FloatObj{Value: 0.5}
+	quarter := &FloatObj{Value: 0.25}
+	zero := &FloatObj{Value: 0}
+	negZero := &FloatObj{Value: math.Copysign(0, -1)}
+
+	if half1.HashKey() != half2.HashKey() {
+		t.Errorf("floats with same content have different hash keys")
+	}
+
+	if half1.HashKey() == quarter.HashKey() {
+		t.Errorf("floats with different content have same hash keys")
+	}
+
+	if zero.HashKey() != negZero.HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+
+	// 1 == 1.0 なので同じキー
+	if (&IntObj{Value: 1}).HashKey() != (&FloatObj{Value: 1}).HashKey() {
+		t.Errorf("1 and 1.0 have different hash keys")
+	}
+
+	if (&IntObj{Value: 0}).HashKey() != negZero.HashKey() {
+		t.Errorf("0 and -0.0 have different hash keys")
+	}
+
+	if (&IntObj{Value: 1}).HashKey() == (&FloatObj{Value: 1.5}).HashKey() {
+		t.Errorf("1 and 1.5 have same hash keys")
+	}
+
+	if (&FloatObj{Value: 1e300}).HashKey() == (&FloatObj{Value: math.Inf(1)}).HashKey() {
+		t.Errorf("1e300 and +Inf have same hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value  float64
+		expect string
+	}{
+		{3.14, "3.14"},
+		{3, "3.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		f := &FloatObj{Value: tt.value}
+		if f.Inspect() != tt.expect {
+			t.Errorf("Inspect() wrong. expect=%q, got=%q", tt.expect, f.Inspect())
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &IntObj{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	if _, ok := inner.Assign("x", &IntObj{Value: 2}); !ok {
+		t.Fatalf("Assign failed for variable in outer environment")
+	}
+
+	// 定義した外側の環境が書き換わって、内側には作られない
+	if obj, _ := outer.Get("x"); obj.(*IntObj).Value != 2 {
+		t.Errorf("outer x not updated. got=%s", obj.Inspect())
+	}
+	if _, ok := inner.store["x"]; ok {
+		t.Errorf("Assign created x in inner environment")
+	}
+
+	// 内側で定義し直したら内側だけ
+	inner.Set("x", &IntObj{Value: 10})
+	inner.Assign("x", &IntObj{Value: 11})
+	if obj, _ := outer.Get("x"); obj.(*IntObj).Value != 2 {
+		t.Errorf("outer x changed through shadowing variable. got=%s", obj.Inspect())
+	}
+
+	if _, ok := inner.Assign("y", &IntObj{Value: 1}); ok {
+		t.Errorf("Assign succeeded for undefined variable")
+	}
+}
@@ -3,8 +3,11 @@ package parser
 // 構文解析とは、Token構造体をNodeインタフェースを満たした構造体にすること
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/yuya-isaka/go-yuya-monkey/ast"
 	"github.com/yuya-isaka/go-yuya-monkey/lexer"
@@ -15,42 +18,88 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	TERNARY // ? :
+	LOGOR   // ||
+	LOGAND  // &&
 	EQUALS
 	LESSGREATER
+	PIPELINE // |>
+	BITOR    // |
+	BITXOR   // ^
+	BITAND   // &
+	SHIFT    // << >>
 	SUM
 	PRODUCT
 	PREFIX
+	POWER // ** （-2 ** 2 は -(2 ** 2)）
 	CALL
 	INDEX
 )
 
 // 優先順位表
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.ASTERISK: PRODUCT,
-	token.SLASH:    PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.QUESTION:  TERNARY,
+	token.OR:        LOGOR,
+	token.AND:       LOGAND,
+	token.EQ:        EQUALS,
+	token.NOT_EQ:    EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.LT_EQ:     LESSGREATER,
+	token.GT_EQ:     LESSGREATER,
+	token.PIPELINE:  PIPELINE,
+	token.PIPE:      BITOR,
+	token.CARET:     BITXOR,
+	token.AMPERSAND: BITAND,
+	token.LSHIFT:    SHIFT,
+	token.RSHIFT:    SHIFT,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.ASTERISK:  PRODUCT,
+	token.SLASH:     PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.POWER:     POWER,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
 }
 
+// 構文解析のエラー
+// Expected, Gotは「何が来るはずで何が来たか」（わかるときだけ、なければ""）
+type Diagnostic struct {
+	Pos      token.Position
+	Expected string
+	Got      string
+	Msg      string
+}
+
+// file:line:column: msg
+func (d Diagnostic) Error() string {
+	return d.Pos.String() + ": " + d.Msg
+}
+
+// これ以上エラーが出たら諦める（1つの間違いから何十個もエラーが出ても読めない）
+const maxErrors = 10
+
 type Parser struct {
 	lex    *lexer.Lexer
-	errors []string
+	errors []Diagnostic
+
+	panicking bool // 今の文でエラーが出た（次の文まで他のエラーは出さない）
+	gaveUp    bool // エラーが多すぎるので打ち切った
+	blocks    int  // 今いる{}ブロックの深さ
+	guard     bool // matchのifの条件を読んでいる（=>は腕の区切りなので矢印関数にしない）
 
 	curT  token.Token
 	peekT token.Token
+	ahead []token.Token // peekTより先に読んだトークン（矢印関数かどうかの見分け用）
 }
 
 func NewParser(l *lexer.Lexer) *Parser {
 
 	p := &Parser{
 		lex:    l,
-		errors: []string{},
+		errors: []Diagnostic{},
 	}
 
 	p.nextToken()
@@ -68,16 +117,8 @@ func (p *Parser) ParseProgram() *ast.ProgramNode {
 	}
 
 	// 文をトークンの最後まで
-	for !p.curToken(token.EOF) {
-		stmt := p.parseStatement()
-
-		// 構文解析のエラーは、エラー配列にためてnilで返ってくるようにしている
-		if stmt == nil {
-			msg := fmt.Sprintf("文がnilだぜ %T", stmt)
-			p.errors = append(p.errors, msg)
-		} else {
-			node.Statements = append(node.Statements, stmt)
-		}
+	for !p.curToken(token.EOF) && !p.gaveUp {
+		node.Statements = append(node.Statements, p.parseStatementOrRecover())
 
 		// [セミコロン] or [式文なら文の末尾]で返ってきているはず
 		p.nextToken()
@@ -96,24 +137,78 @@ func (p *Parser) parseBlock() *ast.BlockNode {
 
 	p.nextToken()
 
+	p.blocks += 1
+	defer func() { p.blocks -= 1 }()
+
 	// 文をトークンの最後まで（"}"を忘れた場合、次々に"}"が出てくるまで、トークンを勧めながら文を読もうとしてしまうのでそれを避けるために、token.EOFでも確認）
-	for !p.curToken(token.RBRACE) && !p.curToken(token.EOF) {
+	for !p.curToken(token.RBRACE) && !p.curToken(token.EOF) && !p.gaveUp {
+		node.Statements = append(node.Statements, p.parseStatementOrRecover())
 
-		stmt := p.parseStatement()
+		// [セミコロン] or [式文なら文の末尾]で返ってきているはず
+		p.nextToken()
+	}
 
-		// 構文解析のエラーは、エラー配列にためてnilで返ってくるようにしている
-		if stmt == nil {
-			msg := fmt.Sprintf("文がnilだぜ %T", stmt)
-			p.errors = append(p.errors, msg)
-		} else {
-			node.Statements = append(node.Statements, stmt)
+	return node
+}
+
+// 文を1つ読む。エラーが出たらその文はErrorNodeにして、次の文の手前まで読み飛ばす
+func (p *Parser) parseStatementOrRecover() ast.Statement {
+	start := p.curT
+	p.panicking = false
+
+	stmt := p.parseStatement()
+
+	// 構文解析のエラーは、エラー配列にためてnilで返ってくるようにしている（エラーは記録済み）
+	// ILLEGALで止まったときはエラーなしでnilが返ってくる（字句解析のエラーで報告済み）
+	if stmt == nil || p.panicking {
+		p.synchronize()
+		p.panicking = false
+		return &ast.ErrorNode{Token: start}
+	}
+
+	return stmt
+}
+
+// 壊れた文の残りを読み飛ばす
+// 「;」か、次が「}」（ブロックの中だけ）か文の頭のキーワードか、EOFの手前で止まる
+// 途中の{}の中身は数えて丸ごと飛ばす（中の「;」や「let」で止まらないように）
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for !p.curToken(token.EOF) {
+		switch p.curT.Type {
+		case token.LBRACE:
+			depth += 1
+		case token.RBRACE:
+			if depth > 0 {
+				depth -= 1
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 {
+			if p.peekToken(token.EOF) || isStatementStart(p.peekT.Type) {
+				return
+			}
+			if p.peekToken(token.RBRACE) && p.blocks > 0 {
+				return
+			}
 		}
 
-		// [セミコロン] or [式文なら文の末尾]で返ってきているはず
 		p.nextToken()
 	}
+}
 
-	return node
+// 文の頭にしか来ないキーワード
+func isStatementStart(t token.TokenType) bool {
+	switch t {
+	case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+		return true
+	}
+	return false
 }
 
 // --------------------------------------------------------------------------
@@ -128,6 +223,32 @@ func (p *Parser) parseStatement() ast.Statement {
 	case token.RETURN:
 		return p.parseReturn()
 
+	case token.WHILE:
+		return p.parseWhile()
+
+	case token.FOR:
+		return p.parseFor()
+
+	case token.BREAK:
+		return p.endStatement(&ast.BreakNode{Token: p.curT})
+
+	case token.CONTINUE:
+		return p.endStatement(&ast.ContinueNode{Token: p.curT})
+
+	// fn 名前(...) { ... } は関数宣言。fn(...) { ... } は今まで通り関数式
+	case token.FUNCTION:
+		if p.peekToken(token.IDENT) {
+			return p.parseFuncDecl()
+		}
+		return p.parseES()
+
+	// x = 1, x += 1（変数の次が"="なら再代入）
+	case token.IDENT:
+		if isAssignOperator(p.peekT.Type) {
+			return p.parseAssign()
+		}
+		return p.parseES()
+
 	default:
 		return p.parseES() // 式文
 	}
@@ -140,11 +261,27 @@ func (p *Parser) parseLet() ast.Statement {
 
 	// let a = 3;
 	//  ↑
-	if !p.expectPeekToken(token.IDENT) {
+	switch {
+	// let [a, b] = arr;
+	case p.peekToken(token.LBRACKET):
+		p.nextToken()
+		node.Pattern = p.parseArrayPattern()
+
+	// let {name, age} = hash;
+	case p.peekToken(token.LBRACE):
+		p.nextToken()
+		node.Pattern = p.parseHashPattern()
+
+	case p.expectPeekToken(token.IDENT):
+		node.Name = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+	default:
 		return nil
 	}
 
-	node.Name = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+	if node.Name == nil && node.Pattern == nil {
+		return nil
+	}
 
 	// let a = 3;
 	//     ↑
@@ -163,18 +300,19 @@ func (p *Parser) parseLet() ast.Statement {
 	// どちらか
 	// let a = 3;
 	//         ↑
-	// セミコロンのわけがない
+	// セミコロンのわけがない（式がなかったということなので、エラーは記録済み）
 	if p.curToken(token.SEMICOLON) {
-		p.errors = append(p.errors, "\";\" is wrong!!!")
 		return nil
 	}
 
 	p.nextToken()
 
 	// letは";"が必須です
+	// ILLEGALは字句解析のエラーで報告済み
 	if !p.curToken(token.SEMICOLON) {
-		msg := fmt.Sprintf("\";\" is nothing!!! token is %q", p.curT.Type)
-		p.errors = append(p.errors, msg)
+		if !p.curToken(token.ILLEGAL) {
+			p.expectError(p.curT, ";", fmt.Sprintf("missing \";\" after let statement, got %s", p.curT.Type))
+		}
 		return nil
 	}
 
@@ -182,6 +320,166 @@ func (p *Parser) parseLet() ast.Statement {
 	return node
 }
 
+// パターンの先頭で呼ばれて、パターンの最後で終わる
+// 変数（_も）、リテラル、[...]、{...}
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curT.Type {
+	case token.IDENT:
+		return &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return p.parseLiteralPattern()
+
+	// 負の数だけ（-x や -"a" はパターンにならない）
+	case token.MINUS:
+		if !p.peekToken(token.INT) && !p.peekToken(token.FLOAT) {
+			p.expectError(p.peekT, "number", fmt.Sprintf("expected number after - in pattern, got %s", p.peekT.Type))
+			return nil
+		}
+		return p.parseLiteralPattern()
+
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+
+	case token.LBRACE:
+		return p.parseHashPattern()
+
+	// 字句解析のエラーで報告済み
+	case token.ILLEGAL:
+		return nil
+	}
+
+	p.expectError(p.curT, "pattern", fmt.Sprintf("expected pattern, got %s", p.curT.Type))
+	return nil
+}
+
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	node := &ast.LiteralPatternNode{Token: p.curT}
+
+	// -は数字にだけつける（-1 ** 2 などは読まない）
+	if p.curToken(token.MINUS) {
+		minus := &ast.PrefixNode{Token: p.curT, Operator: p.curT.Name}
+		p.nextToken()
+		if minus.Right = p.parseLiteral(); minus.Right == nil {
+			return nil
+		}
+		node.Value = minus
+	} else {
+		node.Value = p.parseLiteral()
+	}
+
+	// 数字の範囲外などのエラーは記録済み
+	if node.Value == nil {
+		return nil
+	}
+
+	return node
+}
+
+// パターンに書けるリテラル
+func (p *Parser) parseLiteral() ast.Expression {
+	switch p.curT.Type {
+	case token.INT:
+		return p.parseInt()
+	case token.FLOAT:
+		return p.parseFloat()
+	case token.STRING:
+		return p.parseString()
+	default:
+		return p.parseBool()
+	}
+}
+
+// "["の位置で呼ばれて、"]"の位置で終わる
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	node := &ast.ArrayPatternNode{Token: p.curT, Elements: []ast.Pattern{}}
+
+	if p.peekToken(token.RBRACKET) {
+		p.nextToken()
+		return node
+	}
+
+	for {
+		// let [first, ...rest] = arr;
+		//             ↑
+		if p.peekToken(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeekToken(token.IDENT) {
+				return nil
+			}
+			node.Rest = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+			break
+		}
+
+		p.nextToken()
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		node.Elements = append(node.Elements, elem)
+
+		if !p.peekToken(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeekToken(token.RBRACKET) {
+		return nil
+	}
+
+	return node
+}
+
+// "{"の位置で呼ばれて、"}"の位置で終わる
+// {name} は {"name": name} と同じ
+func (p *Parser) parseHashPattern() ast.Pattern {
+	node := &ast.HashPatternNode{Token: p.curT, Keys: []*ast.StringNode{}, Values: []ast.Pattern{}}
+
+	if p.peekToken(token.RBRACE) {
+		p.nextToken()
+		return node
+	}
+
+	for {
+		// {"key": パターン}
+		if p.peekToken(token.STRING) {
+			p.nextToken()
+			key := &ast.StringNode{Token: p.curT, Value: p.curT.Name}
+
+			if !p.expectPeekToken(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+
+			value := p.parsePattern()
+			if value == nil {
+				return nil
+			}
+
+			node.Keys = append(node.Keys, key)
+			node.Values = append(node.Values, value)
+		} else {
+			if !p.expectPeekToken(token.IDENT) {
+				return nil
+			}
+			node.Keys = append(node.Keys, &ast.StringNode{Token: p.curT, Value: p.curT.Name})
+			node.Values = append(node.Values, &ast.IdentNode{Token: p.curT, Value: p.curT.Name})
+		}
+
+		if !p.peekToken(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeekToken(token.RBRACE) {
+		return nil
+	}
+
+	return node
+}
+
 func (p *Parser) parseReturn() ast.Statement {
 	node := &ast.ReturnNode{Token: p.curT}
 
@@ -189,18 +487,19 @@ func (p *Parser) parseReturn() ast.Statement {
 
 	node.Value = p.parseExpression(LOWEST)
 
-	// セミコロンのわけがない
+	// セミコロンのわけがない（式がなかったということなので、エラーは記録済み）
 	if p.curToken(token.SEMICOLON) {
-		p.errors = append(p.errors, "\";\" is wrong!!!")
 		return nil
 	}
 
 	p.nextToken()
 
 	// returnは";"が必須です
+	// ILLEGALは字句解析のエラーで報告済み
 	if !p.curToken(token.SEMICOLON) {
-		msg := fmt.Sprintf("\";\" is nothing!!! token is %q", p.curT.Type)
-		p.errors = append(p.errors, msg)
+		if !p.curToken(token.ILLEGAL) {
+			p.expectError(p.curT, ";", fmt.Sprintf("missing \";\" after return statement, got %s", p.curT.Type))
+		}
 		return nil
 	}
 
@@ -212,9 +511,8 @@ func (p *Parser) parseES() ast.Statement {
 	node := &ast.EsNode{Token: p.curT}
 	node.Value = p.parseExpression(LOWEST)
 
-	// セミコロンのわけがない
+	// セミコロンのわけがない（式がなかったということなので、エラーは記録済み）
 	if p.curToken(token.SEMICOLON) {
-		p.errors = append(p.errors, "\";\" is wrong!!!")
 		return nil
 	}
 
@@ -227,6 +525,119 @@ func (p *Parser) parseES() ast.Statement {
 	return node
 }
 
+func (p *Parser) parseFuncDecl() ast.Statement {
+	fn := &ast.FunctionNode{Token: p.curT}
+	node := &ast.FuncDeclNode{Token: p.curT, Function: fn}
+
+	// fn add(a, b) { a + b }
+	//    ↑
+	p.nextToken()
+	node.Name = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+	if !p.parseFunctionRest(fn) {
+		return nil
+	}
+
+	return p.endStatement(node)
+}
+
+func (p *Parser) parseAssign() ast.Statement {
+	name := &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+	// x += 1;
+	//   ↑
+	p.nextToken()
+	node := &ast.AssignNode{Token: p.curT, Name: name, Operator: p.curT.Name}
+
+	// x += 1;
+	//      ↑
+	p.nextToken()
+	node.Value = p.parseExpression(LOWEST)
+
+	// セミコロンのわけがない（式がなかったということなので、エラーは記録済み）
+	if p.curToken(token.SEMICOLON) {
+		return nil
+	}
+
+	// 式文と同じでセミコロンなしOK
+	return p.endStatement(node)
+}
+
+func isAssignOperator(t token.TokenType) bool {
+	switch t {
+	case token.ASSIGN, token.PLUS_EQ, token.MINUS_EQ, token.MUL_EQ, token.DIV_EQ:
+		return true
+	}
+	return false
+}
+
+func (p *Parser) parseWhile() ast.Statement {
+	node := &ast.WhileNode{Token: p.curT}
+
+	// while (i < 3) { ... }
+	//       ↑
+	if !p.expectPeekToken(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	node.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeekToken(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeekToken(token.LBRACE) {
+		return nil
+	}
+
+	node.Body = p.parseBlock()
+
+	return p.endStatement(node)
+}
+
+func (p *Parser) parseFor() ast.Statement {
+	node := &ast.ForNode{Token: p.curT}
+
+	// for (x in xs) { ... }
+	//     ↑
+	if !p.expectPeekToken(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeekToken(token.IDENT) {
+		return nil
+	}
+	node.Variable = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+	if !p.expectPeekToken(token.IN) {
+		return nil
+	}
+	p.nextToken()
+
+	node.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeekToken(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeekToken(token.LBRACE) {
+		return nil
+	}
+
+	node.Body = p.parseBlock()
+
+	return p.endStatement(node)
+}
+
+// ";"なしでもOKな文の終わり（あれば";"まで進める）
+func (p *Parser) endStatement(node ast.Statement) ast.Statement {
+	if p.peekToken(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return node
+}
+
 // --------------------------------------------------------------------------
 
 // precedenceに入っている優先順位は、真左の優先順位
@@ -238,23 +649,39 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	// 下の関数たちはギリギリまで進める
 	switch p.curT.Type {
 	case token.IDENT:
+		// x => x * 2
+		if p.peekToken(token.ARROW) && !p.guard {
+			left = p.parseArrowFunction()
+			break
+		}
 		left = p.parseIdent()
 
 	case token.INT:
 		left = p.parseInt()
 
-	case token.BANG, token.MINUS:
+	case token.FLOAT:
+		left = p.parseFloat()
+
+	case token.BANG, token.MINUS, token.TILDE:
 		left = p.parsePrefix()
 
 	case token.TRUE, token.FALSE:
 		left = p.parseBool()
 
 	case token.LPAREN:
+		// (x, y) => x + y
+		if !p.guard && p.isArrowParameters() {
+			left = p.parseArrowFunction()
+			break
+		}
 		left = p.parseGroup()
 
 	case token.IF:
 		left = p.parseIf()
 
+	case token.MATCH:
+		left = p.parseMatch()
+
 	// lexerのキーワード登録から割り当てられる。fnが来たらtoken.FUNCTION
 	case token.FUNCTION:
 		left = p.parseFunction()
@@ -262,22 +689,34 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	case token.STRING:
 		left = p.parseString()
 
+	case token.INTERP_START:
+		left = p.parseInterp()
+
 	case token.LBRACKET:
 		left = p.parseArray()
 
 	case token.LBRACE:
 		left = p.parseHash()
 
+	// 字句解析のエラーで報告済み
+	// エラーは増やさずに、この文の残りのエラーも出さないようにする
+	case token.ILLEGAL:
+		p.panicking = true
+		return &ast.ErrorNode{Token: p.curT}
+
 	default:
 		msg := fmt.Sprintf("no prefix parse function for %s found", p.curT.Type)
-		p.errors = append(p.errors, msg)
-		return nil
+		p.addDiagnostic(Diagnostic{Pos: p.curT.Pos, Expected: "expression", Got: string(p.curT.Type), Msg: msg})
+		return &ast.ErrorNode{Token: p.curT}
 	}
 
 	// 左辺 vs 右辺
 	for !p.peekToken(token.SEMICOLON) && precedence < p.peekPrecedence() {
 		switch p.peekT.Type {
-		case token.EQ, token.NOT_EQ, token.LT, token.GT, token.PLUS, token.MINUS, token.ASTERISK, token.SLASH:
+		case token.EQ, token.NOT_EQ, token.LT, token.GT, token.LT_EQ, token.GT_EQ,
+			token.PLUS, token.MINUS, token.ASTERISK, token.SLASH, token.PERCENT, token.POWER,
+			token.AMPERSAND, token.PIPE, token.CARET, token.LSHIFT, token.RSHIFT,
+			token.AND, token.OR:
 			p.nextToken()
 			left = p.parseInfix(left)
 
@@ -286,6 +725,14 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 			p.nextToken()
 			left = p.parseCall(left)
 
+		case token.PIPELINE:
+			p.nextToken()
+			left = p.parsePipeline(left)
+
+		case token.QUESTION:
+			p.nextToken()
+			left = p.parseTernary(left)
+
 		// 関数の後の[は、関数の評価された後のleftが入ってくる。それを配列の左辺として使う
 		// 基本関数の左辺は変数しかこなくて、その場合precedenceは変数の優先順位(つまりLOWEST)
 		// 関数より優先順位が低くても問題にはならない
@@ -296,9 +743,14 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 			p.nextToken()
 			left = p.parseIndex(left)
 
+		// h.key, h.method(args)
+		case token.DOT:
+			p.nextToken()
+			left = p.parseDot(left)
+
+		// 優先順位表にあるのにここにないのは、パーサーの書き忘れ
 		default:
-			msg := fmt.Sprintf("Infix Error: %T (%+v)", p.peekT, p.peekT)
-			p.errors = append(p.errors, msg)
+			p.expectError(p.peekT, "operator", fmt.Sprintf("no infix parse function for %s found", p.peekT.Type))
 			return nil
 		}
 	}
@@ -310,7 +762,35 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 func (p *Parser) nextToken() {
 	p.curT = p.peekT
-	p.peekT = p.lex.NextToken()
+
+	if len(p.ahead) > 0 {
+		p.peekT = p.ahead[0]
+		p.ahead = p.ahead[1:]
+		return
+	}
+	p.peekT = p.readToken()
+}
+
+// peekTのn個先のトークン（0ならpeekT）
+// 読んだトークンはaheadにためておいて、nextTokenで順番に使う
+func (p *Parser) peekAhead(n int) token.Token {
+	if n == 0 {
+		return p.peekT
+	}
+	for len(p.ahead) < n {
+		p.ahead = append(p.ahead, p.readToken())
+	}
+	return p.ahead[n-1]
+}
+
+func (p *Parser) readToken() token.Token {
+	tok := p.lex.NextToken()
+
+	// コメントは構文には関係ないので飛ばす
+	for tok.Type == token.COMMENT {
+		tok = p.lex.NextToken()
+	}
+	return tok
 }
 
 func (p *Parser) curToken(t token.TokenType) bool {
@@ -325,9 +805,13 @@ func (p *Parser) expectPeekToken(t token.TokenType) bool {
 	if p.peekToken(t) {
 		p.nextToken()
 		return true
+	} else if p.peekToken(token.ILLEGAL) {
+		// 字句解析のエラーで報告済み（この文は壊れているので直しに入る）
+		p.panicking = true
+		return false
 	} else {
 		msg := fmt.Sprintf("expected nexttoken to be %s, got %s instead", t, p.peekT.Type)
-		p.errors = append(p.errors, msg)
+		p.expectError(p.peekT, string(t), msg)
 		return false
 	}
 }
@@ -354,8 +838,51 @@ func (p *Parser) curPrecedence() int {
 
 //--------------------
 
+// エラーメッセージ（位置つきの文字列）
 func (p *Parser) Errors() []string {
-	return p.errors
+	diags := p.Diagnostics()
+	msgs := make([]string, 0, len(diags))
+	for _, d := range diags {
+		msgs = append(msgs, d.Error())
+	}
+	return msgs
+}
+
+// エラーを位置・期待したもの・来たもの・メッセージに分けたまま返す
+// 字句解析のエラーが先（ILLEGALのせいで起きたパーサーのエラーより原因に近いから）
+func (p *Parser) Diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	for _, e := range p.lex.Diagnostics() {
+		diags = append(diags, Diagnostic{Pos: e.Pos, Msg: e.Msg})
+	}
+	return append(diags, p.errors...)
+}
+
+// メッセージだけのエラー
+func (p *Parser) addError(pos token.Position, msg string) {
+	p.addDiagnostic(Diagnostic{Pos: pos, Msg: msg})
+}
+
+// expectedが来るはずだったのにtokが来た
+func (p *Parser) expectError(tok token.Token, expected string, msg string) {
+	p.addDiagnostic(Diagnostic{Pos: tok.Pos, Expected: expected, Got: string(tok.Type), Msg: msg})
+}
+
+// 文の中で最初のエラーだけためる（2つ目からは最初のエラーのせいで出たものがほとんど）
+// 多すぎたら打ち切る
+func (p *Parser) addDiagnostic(d Diagnostic) {
+	if p.panicking || p.gaveUp {
+		return
+	}
+	p.panicking = true
+
+	if len(p.errors) >= maxErrors {
+		p.errors = append(p.errors, Diagnostic{Pos: d.Pos, Msg: "too many errors"})
+		p.gaveUp = true
+		return
+	}
+
+	p.errors = append(p.errors, d)
 }
 
 //--------------------
@@ -365,17 +892,49 @@ func (p *Parser) parseIdent() ast.Expression {
 }
 
 func (p *Parser) parseInt() ast.Expression {
-	value, err := strconv.ParseInt(p.curT.Name, 0, 64)
+	value, err := parseIntLiteral(p.curT.Name)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curT.Name)
-		p.errors = append(p.errors, msg)
+		var msg string
+		if errors.Is(err, strconv.ErrRange) {
+			msg = fmt.Sprintf("integer literal %s out of range (max %d)", p.curT.Name, math.MaxInt64)
+		} else {
+			msg = fmt.Sprintf("invalid integer literal %q", p.curT.Name)
+		}
+		p.addError(p.curT.Pos, msg)
 		return nil
 	}
 
 	return &ast.IntNode{Token: p.curT, Value: value}
 }
 
+// 10進, 0x(16進), 0o(8進), 0b(2進) と "_" 区切りを読む（書き方のチェックはlexerで済んでいる）
+// strconvの基数0に任せると"017"が8進になってしまうので、接頭辞なしは10進で読む
+func parseIntLiteral(name string) (int64, error) {
+	if len(name) > 1 && name[0] == '0' && strings.ContainsRune("xXoObB", rune(name[1])) {
+		return strconv.ParseInt(name, 0, 64)
+	}
+
+	return strconv.ParseInt(strings.ReplaceAll(name, "_", ""), 10, 64)
+}
+
+func (p *Parser) parseFloat() ast.Expression {
+	value, err := strconv.ParseFloat(p.curT.Name, 64)
+
+	if err != nil {
+		var msg string
+		if errors.Is(err, strconv.ErrRange) {
+			msg = fmt.Sprintf("float literal %s out of range", p.curT.Name)
+		} else {
+			msg = fmt.Sprintf("invalid float literal %q", p.curT.Name)
+		}
+		p.addError(p.curT.Pos, msg)
+		return nil
+	}
+
+	return &ast.FloatNode{Token: p.curT, Value: value}
+}
+
 func (p *Parser) parsePrefix() ast.Expression {
 
 	node := &ast.PrefixNode{
@@ -403,6 +962,11 @@ func (p *Parser) parseInfix(left ast.Expression) ast.Expression {
 	// ここが超大事
 	// parseExpressionを呼び出すときの、precedenceをどうするかで大きく変わってくる。
 	precedence := p.curPrecedence()
+	// **だけは右結合（2 ** 3 ** 2 は 2 ** (3 ** 2)）
+	// 1つ下げておくと、右にある**に負けて右に吸い込まれる
+	if p.curToken(token.POWER) {
+		precedence -= 1
+	}
 	p.nextToken()
 	// ここで式を再度呼ぶの痺れるな〜
 	// ここが痺れるポイント覚えとけよー
@@ -426,12 +990,21 @@ func (p *Parser) parseGroup() ast.Expression {
 
 	p.nextToken()
 
+	// かっこの中の=>は矢印関数
+	guard := p.guard
+	p.guard = false
 	node := p.parseExpression(LOWEST)
+	p.guard = guard
 
 	if !p.expectPeekToken(token.RPAREN) {
 		return nil
 	}
 
+	// x |> (g(1)) のg(1)には引数を足さないので印をつけておく
+	if call, ok := node.(*ast.CallNode); ok {
+		call.Grouped = true
+	}
+
 	return node
 }
 
@@ -460,6 +1033,18 @@ func (p *Parser) parseIf() ast.Expression {
 	if p.peekToken(token.ELSE) {
 		p.nextToken()
 
+		// else if (...) { } は else { if (...) { } } にする
+		if p.peekToken(token.IF) {
+			p.nextToken()
+			tok := p.curT
+			alternative := p.parseIf()
+			if alternative == nil {
+				return nil
+			}
+			node.Alternative = &ast.BlockNode{Token: tok, Statements: []ast.Statement{&ast.EsNode{Token: tok, Value: alternative}}}
+			return node
+		}
+
 		if !p.expectPeekToken(token.LBRACE) {
 			return nil
 		}
@@ -470,52 +1055,209 @@ func (p *Parser) parseIf() ast.Expression {
 	return node
 }
 
-func (p *Parser) parseFunction() ast.Expression {
+// '?'の位置で呼ばれて、':'の後ろの式の最後で終わる
+// 右結合（a ? b : c ? d : e は a ? b : (c ? d : e)）
+func (p *Parser) parseTernary(condition ast.Expression) ast.Expression {
+	node := &ast.TernaryNode{Token: p.curT, Condition: condition}
 
-	node := &ast.FunctionNode{Token: p.curT}
+	p.nextToken()
+	node.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeekToken(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+
+	// 1つ下げておくと、右にある?に負けて右に吸い込まれる
+	node.Alternative = p.parseExpression(TERNARY - 1)
+
+	return node
+}
+
+// match (値) { パターン => 式, パターン if 条件 => 式 }
+// 最後の腕のカンマはあってもなくてもいい
+func (p *Parser) parseMatch() ast.Expression {
+
+	node := &ast.MatchNode{Token: p.curT, Arms: []*ast.MatchArm{}}
 
 	if !p.expectPeekToken(token.LPAREN) {
 		return nil
 	}
+	p.nextToken()
 
-	node.Parameters = p.parseParameters()
+	node.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeekToken(token.RPAREN) {
+		return nil
+	}
 
 	if !p.expectPeekToken(token.LBRACE) {
 		return nil
 	}
 
-	node.Body = p.parseBlock()
+	for !p.peekToken(token.RBRACE) {
+		p.nextToken()
+
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if p.peekToken(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			guard := p.guard
+			p.guard = true
+			arm.Guard = p.parseExpression(LOWEST)
+			p.guard = guard
+		}
+
+		if !p.expectPeekToken(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+
+		arm.Body = p.parseExpression(LOWEST)
+		node.Arms = append(node.Arms, arm)
+
+		if !p.peekToken(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeekToken(token.RBRACE) {
+		return nil
+	}
 
 	return node
 }
 
-// 返る先の型が指定されているから、返り値の型はast.Expressionではなく*ast.IdentNode
-func (p *Parser) parseParameters() []*ast.IdentNode {
+func (p *Parser) parseFunction() ast.Expression {
 
-	nodes := []*ast.IdentNode{}
+	node := &ast.FunctionNode{Token: p.curT}
 
-	if p.peekToken(token.RPAREN) {
-		p.nextToken()
-		return nodes
+	if !p.parseFunctionRest(node) {
+		return nil
+	}
+
+	return node
+}
+
+// "("の位置で、対応する")"の次が"=>"かどうか
+func (p *Parser) isArrowParameters() bool {
+	depth := 1
+	for i := 0; ; i++ {
+		switch p.peekAhead(i).Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth == 0 {
+				return p.peekAhead(i+1).Type == token.ARROW
+			}
+		case token.EOF:
+			return false
+		}
 	}
+}
 
+// x => 式、(x, y = 1, ...rest) => 式
+// 「fn(引数) { 式 }」と同じFunctionNodeにする
+// 変数か"("の位置で呼ばれて、式の最後で終わる
+func (p *Parser) parseArrowFunction() ast.Expression {
+	node := &ast.FunctionNode{Token: token.Token{Type: token.FUNCTION, Name: "fn", Pos: p.curT.Pos}}
+
+	if p.curToken(token.IDENT) {
+		node.Parameters = []*ast.IdentNode{{Token: p.curT, Value: p.curT.Name}}
+		node.Defaults = []ast.Expression{nil}
+	} else if !p.parseParameters(node) {
+		return nil
+	}
+
+	if !p.expectPeekToken(token.ARROW) {
+		return nil
+	}
+	arrow := p.curT
 	p.nextToken()
 
-	node := &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
-	nodes = append(nodes, node)
+	body := p.parseExpression(LOWEST)
+	node.Body = &ast.BlockNode{Token: arrow, Statements: []ast.Statement{&ast.EsNode{Token: arrow, Value: body}}}
 
-	for p.peekToken(token.COMMA) {
-		p.nextToken()
+	return node
+}
+
+// fn名前の後ろの「(引数) { 本体 }」を読む（関数式と関数宣言で共通）
+func (p *Parser) parseFunctionRest(node *ast.FunctionNode) bool {
+	if !p.expectPeekToken(token.LPAREN) {
+		return false
+	}
+
+	if !p.parseParameters(node) {
+		return false
+	}
+
+	if !p.expectPeekToken(token.LBRACE) {
+		return false
+	}
+
+	node.Body = p.parseBlock()
+
+	return true
+}
+
+// (a, b = 2, ...rest) を読んでnodeに入れる。")"で終わる
+// デフォルト値のある引数の後ろは全部デフォルト値がいる。...restは最後に1つだけ
+func (p *Parser) parseParameters(node *ast.FunctionNode) bool {
+
+	node.Parameters = []*ast.IdentNode{}
+	node.Defaults = []ast.Expression{}
+
+	if p.peekToken(token.RPAREN) {
 		p.nextToken()
-		node := &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
-		nodes = append(nodes, node)
+		return true
 	}
 
-	if !p.expectPeekToken(token.RPAREN) {
-		return nil
+	for {
+		// fn(first, ...rest)
+		//           ↑
+		if p.peekToken(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeekToken(token.IDENT) {
+				return false
+			}
+			node.Rest = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+			break
+		}
+
+		if !p.expectPeekToken(token.IDENT) {
+			return false
+		}
+		param := &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+		// fn(a, b = 2)
+		//         ↑
+		var value ast.Expression
+		if p.peekToken(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(LOWEST)
+		} else if n := len(node.Defaults); n > 0 && node.Defaults[n-1] != nil {
+			msg := fmt.Sprintf("parameter %s without default follows parameter with default", param.Value)
+			p.addError(param.Token.Pos, msg)
+			return false
+		}
+
+		node.Parameters = append(node.Parameters, param)
+		node.Defaults = append(node.Defaults, value)
+
+		if !p.peekToken(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	return nodes
+	return p.expectPeekToken(token.RPAREN)
 }
 
 func (p *Parser) parseCall(function ast.Expression) ast.Expression {
@@ -525,6 +1267,28 @@ func (p *Parser) parseCall(function ast.Expression) ast.Expression {
 	return node
 }
 
+// x |> f(a) は f(x, a)、x |> f は f(x) にする（新しいノードは作らない）
+// 左結合なので xs |> map(f) |> filter(g) は filter(map(xs, f), g)
+// x |> (g(1)) はg(1)の結果を呼ぶ（g(1)(x)）
+func (p *Parser) parsePipeline(left ast.Expression) ast.Expression {
+	tok := p.curT
+	precedence := p.curPrecedence()
+	p.nextToken()
+
+	right := p.parseExpression(precedence)
+	switch right.(type) {
+	case nil, *ast.ErrorNode:
+		return right
+	}
+
+	if call, ok := right.(*ast.CallNode); ok && !call.Grouped {
+		call.Arguments = append([]ast.Expression{left}, call.Arguments...)
+		return call
+	}
+
+	return &ast.CallNode{Token: tok, Function: right, Arguments: []ast.Expression{left}}
+}
+
 func (p *Parser) parseArray() ast.Expression {
 	array := &ast.ArrayNode{Token: p.curT}
 	array.Values = p.parseExpressions(token.RBRACKET)
@@ -537,6 +1301,11 @@ func (p *Parser) parseExpressions(end token.TokenType) []ast.Expression {
 
 	nodes := []ast.Expression{}
 
+	// 引数や要素の=>は矢印関数
+	guard := p.guard
+	p.guard = false
+	defer func() { p.guard = guard }()
+
 	// 要素なしでreturn
 	// endチェック
 	if p.peekToken(end) {
@@ -567,13 +1336,58 @@ func (p *Parser) parseString() ast.Expression {
 	return &ast.StringNode{Token: p.curT, Value: p.curT.Name}
 }
 
+// INTERP_STARTの位置で呼ばれて、INTERP_ENDの位置で終わる
+func (p *Parser) parseInterp() ast.Expression {
+	node := &ast.InterpNode{Token: p.curT, Strings: []string{p.curT.Name}, Exprs: []ast.Expression{}}
+
+	for {
+		if p.peekToken(token.INTERP_MID) || p.peekToken(token.INTERP_END) {
+			p.expectError(p.peekT, "expression", "empty expression in string interpolation")
+			return nil
+		}
+		p.nextToken()
+		node.Exprs = append(node.Exprs, p.parseExpression(LOWEST))
+
+		switch {
+		case p.peekToken(token.INTERP_MID):
+			p.nextToken()
+			node.Strings = append(node.Strings, p.curT.Name)
+
+		case p.peekToken(token.INTERP_END):
+			p.nextToken()
+			node.Strings = append(node.Strings, p.curT.Name)
+			return node
+
+		// 字句解析のエラーで報告済み（エラーは増やさずにこの文を捨てる）
+		case p.peekToken(token.ILLEGAL):
+			p.panicking = true
+			return &ast.ErrorNode{Token: p.peekT}
+
+		default:
+			p.expectError(p.peekT, "}", fmt.Sprintf("expected } to close string interpolation, got %s", p.peekT.Type))
+			return nil
+		}
+	}
+}
+
 func (p *Parser) parseIndex(left ast.Expression) ast.Expression {
 	// 呼ばれるときは先頭
 	node := &ast.IndexNode{Token: p.curT, Left: left}
+
+	// x[:end]
+	if p.peekToken(token.COLON) {
+		return p.parseSlice(node.Token, left, nil)
+	}
+
 	p.nextToken()
 	node.Index = p.parseExpression(LOWEST)
 	// parseExpressionの後は、その式の最後で終わっている
 
+	// x[start:end]
+	if p.peekToken(token.COLON) {
+		return p.parseSlice(node.Token, left, node.Index)
+	}
+
 	// 間違っている場合
 	if !p.expectPeekToken(token.RBRACKET) {
 		return nil
@@ -582,6 +1396,37 @@ func (p *Parser) parseIndex(left ast.Expression) ast.Expression {
 	return node
 }
 
+// '.'の位置で呼ばれて、キーの位置で終わる
+func (p *Parser) parseDot(left ast.Expression) ast.Expression {
+	node := &ast.DotNode{Token: p.curT, Left: left}
+
+	if !p.expectPeekToken(token.IDENT) {
+		return nil
+	}
+	node.Key = &ast.IdentNode{Token: p.curT, Value: p.curT.Name}
+
+	return node
+}
+
+// ':'の手前で呼ばれて、']'の位置で終わる
+func (p *Parser) parseSlice(tok token.Token, left, start ast.Expression) ast.Expression {
+	node := &ast.SliceNode{Token: tok, Left: left, Start: start}
+
+	p.nextToken()
+
+	// x[start:]
+	if !p.peekToken(token.RBRACKET) {
+		p.nextToken()
+		node.End = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeekToken(token.RBRACKET) {
+		return nil
+	}
+
+	return node
+}
+
 func (p *Parser) parseHash() ast.Expression {
 	hash := &ast.HashNode{Token: p.curT}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/yuya-isaka/go-yuya-monkey/ast"
@@ -63,6 +64,68 @@ func TestLetStatements(t *testing.T) {
 
 //----------------------------------
 
+func TestLetDestructuring(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"let [a, b] = pair;", "let [a, b] = pair;"},
+		{"let [first, ...rest] = [1, 2, 3];", "let [first, ...rest] = [1, 2, 3];"},
+		{"let [...all] = xs;", "let [...all] = xs;"},
+		{"let [] = xs;", "let [] = xs;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{"let [a, _, [b, c]] = xs;", "let [a, _, [b, c]] = xs;"},
+		{`let {"full name": n, age, "tags": [t]} = p;`, `let {"full name": n, age, "tags": [t]} = p;`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetNode)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetNode. got=%T", program.Statements[0])
+		}
+
+		if stmt.Pattern == nil || stmt.Name != nil {
+			t.Errorf("%q - expected pattern instead of name. got=%+v", tt.input, stmt)
+		}
+
+		if program.String() != tt.expect {
+			t.Errorf("expected=%q, got=%q", tt.expect, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input  string
+		expect string
+	}{
+		{"let [a, +] = xs;", "1:9: expected pattern, got +"},
+		{"let [a, -x] = xs;", "1:10: expected number after - in pattern, got IDENT"},
+		{"let [...a, b] = xs;", "1:10: expected nexttoken to be ], got , instead"},
+		{"let {name: n} = h;", "1:10: expected nexttoken to be }, got : instead"},
+		{"let [a, b = xs;", "1:11: expected nexttoken to be ], got = instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input       string
@@ -163,6 +226,109 @@ func TestIntegerContentExpressions(t *testing.T) {
 	// 上記のアサーションを設ける
 }
 
+func TestIntegerLiteralForms(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect int64
+	}{
+		{"0x1F", 31},
+		{"0XfF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0b1111_0000", 240},
+		{"017", 17},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.EsNode)
+		integer, ok := stmt.Value.(*ast.IntNode)
+		if !ok {
+			t.Fatalf("exp not *ast.IntNode. got=%T", stmt.Value)
+		}
+		if integer.Value != tt.expect {
+			t.Errorf("integer.Value not %d. got=%d", tt.expect, integer.Value)
+		}
+		if integer.Token.Name != tt.input {
+			t.Errorf("integer.Token.Name not %s. got=%s", tt.input, integer.Token.Name)
+		}
+	}
+}
+
+func TestNumberLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"9223372036854775808", "1:1: integer literal 9223372036854775808 out of range (max 9223372036854775807)"},
+		{"let x = 0xFFFFFFFFFFFFFFFFF;", "1:9: integer literal 0xFFFFFFFFFFFFFFFFF out of range (max 9223372036854775807)"},
+		{"0b102", "1:1: invalid digit '2' in binary literal"},
+		{"0x", "1:1: hexadecimal literal has no digits"},
+		{"0o19", "1:1: invalid digit '9' in octal literal"},
+		{"1__000", "1:1: '_' must separate successive digits"},
+		{"1_000_", "1:1: '_' must separate successive digits"},
+		{"1e999", "1:1: float literal 1e999 out of range"},
+		{"1_.5", "1:1: '_' must separate successive digits"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
+	}
+}
+
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9", 1e-9},
+		{"2.5E3", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.EsNode)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.EsNode. got=%T", program.Statements[0])
+		}
+
+		float, ok := stmt.Value.(*ast.FloatNode)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatNode. got=%T", stmt.Value)
+		}
+		if float.Value != tt.expect {
+			t.Errorf("float.Value not %g. got=%g", tt.expect, float.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -220,6 +386,15 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 		{"foobar + barfoo;", "foobar", "+", "barfoo"},
 		{"foobar - barfoo;", "foobar", "-", "barfoo"},
 		{"foobar * barfoo;", "foobar", "*", "barfoo"},
@@ -308,6 +483,66 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a + b * c + d / e - f",
 			"(((a + (b * c)) + (d / e)) - f)",
 		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a == b && c < d || !e",
+			"(((a == b) && (c < d)) || (!e))",
+		},
+		{
+			"xs |> map(f) |> filter(g)",
+			"filter(map(xs, f), g)",
+		},
+		{
+			"a + b |> f |> g(1) == c",
+			"(g(f((a + b)), 1) == c)",
+		},
+		{
+			"xs |> len == 3",
+			"(len(xs) == 3)",
+		},
+		{
+			"a && b |> f",
+			"(a && f(b))",
+		},
+		{
+			"a || b |> f",
+			"(a || f(b))",
+		},
+		{
+			"x |> f & 1",
+			"(f & 1)(x)",
+		},
+		{
+			"x |> (g(1))",
+			"g(1)(x)",
+		},
+		{
+			"x |> (f)(1)",
+			"f(x, 1)",
+		},
+		{
+			"x |> g((a))",
+			"g(x, a)",
+		},
+		{
+			"a | b |> f",
+			"f((a | b))",
+		},
+		{
+			"x |> fn(y) { y }",
+			"fn(y) y(x)",
+		},
+		{
+			"a && b && c",
+			"((a && b) && c)",
+		},
+		{
+			"a | b || c & d",
+			"((a | b) || (c & d))",
+		},
 		{
 			"3 + 4; -5 * 5",
 			"(3 + 4)((-5) * 5)",
@@ -360,6 +595,46 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * [1, 2, 3, 4][b * c] * d",
 			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
 		},
+		{
+			"a[1:b + 1][0]",
+			"((a[1:(b + 1)])[0])",
+		},
+		{
+			"-s[:2] + s[-1:]",
+			"((-(s[:2])) + (s[(-1):]))",
+		},
+		{
+			"a[:]",
+			"(a[:])",
+		},
+		{
+			"-a.b.c * 2",
+			"((-((a.b).c)) * 2)",
+		},
+		{
+			"h.items[0].greet(1, x.y)",
+			"(((h.items)[0]).greet)(1, (x.y))",
+		},
+		{
+			"xs |> h.f(1)",
+			"(h.f)(xs, 1)",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a || b ? c + 1 : d == e",
+			"((a || b) ? (c + 1) : (d == e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"f(a ? b : c, x => x ? 1 : 2)",
+			"f((a ? b : c), fn(x) (x ? 1 : 2))",
+		},
 		{
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
@@ -368,6 +643,42 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a, b)[3]",
 			"(add(a, b)[3])",
 		},
+		{
+			"a <= b == b >= a",
+			"((a <= b) == (b >= a))",
+		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"2 * 3 ** 2",
+			"(2 * (3 ** 2))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"1 << 2 + 3",
+			"(1 << (2 + 3))",
+		},
+		{
+			"a & b == c",
+			"((a & b) == c)",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -484,34 +795,338 @@ func TestIfElseExpression(t *testing.T) {
 		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Value)
 	}
 
-	if !testInfixExpression(t, expression.Condition, "x", "<", "y") {
-		return
+	if !testInfixExpression(t, expression.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(expression.Consequence.Statements) != 1 {
+		t.Errorf("consequence is not 1 statements. got=%d\n", len(expression.Consequence.Statements))
+	}
+
+	consequence, ok := expression.Consequence.Statements[0].(*ast.EsNode)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", expression.Consequence.Statements[0])
+	}
+
+	if !testIdentifier(t, consequence.Value, "x") {
+		return
+	}
+
+	if len(expression.Alternative.Statements) != 1 {
+		t.Errorf("alternative is not 1 statements. got=%d\n", len(expression.Alternative.Statements))
+	}
+
+	alternative, ok := expression.Alternative.Statements[0].(*ast.EsNode)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", expression.Alternative.Statements[0])
+	}
+
+	if !testIdentifier(t, alternative.Value, "y") {
+		return
+	}
+}
+
+func TestElseIfExpression(t *testing.T) {
+	input := `if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.EsNode)
+	node, ok := stmt.Value.(*ast.IfNode)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.IfNode. got=%T", stmt.Value)
+	}
+
+	// else if は else { if ... } と同じ形
+	for _, cond := range []string{"b", "c"} {
+		if node.Alternative == nil || len(node.Alternative.Statements) != 1 {
+			t.Fatalf("alternative is not 1 statement. got=%+v", node.Alternative)
+		}
+		nested, ok := node.Alternative.Statements[0].(*ast.EsNode).Value.(*ast.IfNode)
+		if !ok {
+			t.Fatalf("alternative is not ast.IfNode. got=%T", node.Alternative.Statements[0])
+		}
+		if !testIdentifier(t, nested.Condition, cond) {
+			return
+		}
+		node = nested
+	}
+
+	if node.Alternative == nil || node.Alternative.String() != "4" {
+		t.Errorf("last alternative wrong. got=%+v", node.Alternative)
+	}
+
+	if program.String() != "ifa 1else ifb 2else ifc 3else 4" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+
+	errorTests := []struct {
+		input  string
+		expect string
+	}{
+		{"if (a) { 1 } else if b { 2 }", "1:22: expected nexttoken to be (, got IDENT instead"},
+		{"if (a) { 1 } else 2", "1:19: expected nexttoken to be {, got INT instead"},
+		{"a ? b", "1:6: expected nexttoken to be :, got EOF instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
+	}
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectName     string
+		expectOperator string
+		expectValue    interface{}
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"y += true", "y", "+=", true},
+		{"foobar -= y;", "foobar", "-=", "y"},
+		{"z *= 2", "z", "*=", 2},
+		{"z /= 2;", "z", "/=", 2},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.AssignNode)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.AssignNode. got=%T", program.Statements[0])
+		}
+
+		if stmt.Name.Value != tt.expectName {
+			t.Errorf("stmt.Name.Value not %q. got=%q", tt.expectName, stmt.Name.Value)
+		}
+
+		if stmt.Operator != tt.expectOperator {
+			t.Errorf("stmt.Operator not %q. got=%q", tt.expectOperator, stmt.Operator)
+		}
+
+		if !testContentExpression(t, stmt.Value, tt.expectValue) {
+			return
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x; break; continue }`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileNode)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileNode. got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf("body is not 3 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakNode); !ok {
+		t.Errorf("Statements[1] is not ast.BreakNode. got=%T", stmt.Body.Statements[1])
+	}
+
+	if _, ok := stmt.Body.Statements[2].(*ast.ContinueNode); !ok {
+		t.Errorf("Statements[2] is not ast.ContinueNode. got=%T", stmt.Body.Statements[2])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (x in [1, 2]) { x } let y = 1;`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 2, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForNode)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForNode. got=%T", program.Statements[0])
+	}
+
+	if stmt.Variable.Value != "x" {
+		t.Errorf("stmt.Variable wrong. got=%q", stmt.Variable.Value)
+	}
+
+	if stmt.Iterable.String() != "[1, 2]" {
+		t.Errorf("stmt.Iterable wrong. got=%q", stmt.Iterable.String())
+	}
+
+	if stmt.String() != "for(x in [1, 2]) x" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+
+	if !testLetStatementIs(t, program.Statements[1], "y") {
+		return
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) { 0 => "zero", [a, ...rest] if a > 0 => a, {"k": -1.5, name} => name, _ => null, }`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.EsNode)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.EsNode. got=%T", program.Statements[0])
+	}
+
+	match, ok := stmt.Value.(*ast.MatchNode)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.MatchNode. got=%T", stmt.Value)
+	}
+
+	if len(match.Arms) != 4 {
+		t.Fatalf("match.Arms does not contain 4 arms. got=%d", len(match.Arms))
+	}
+
+	if _, ok := match.Arms[0].Pattern.(*ast.LiteralPatternNode); !ok {
+		t.Errorf("match.Arms[0].Pattern is not ast.LiteralPatternNode. got=%T", match.Arms[0].Pattern)
+	}
+
+	if match.Arms[1].Guard == nil || match.Arms[1].Guard.String() != "(a > 0)" {
+		t.Errorf("match.Arms[1].Guard wrong. got=%v", match.Arms[1].Guard)
+	}
+
+	expect := `match(x) { 0 => zero, [a, ...rest] if (a > 0) => a, {"k": (-1.5), name} => name, _ => null }`
+	if match.String() != expect {
+		t.Errorf("match.String() wrong.\nexpect=%q\ngot=%q", expect, match.String())
 	}
 
-	if len(expression.Consequence.Statements) != 1 {
-		t.Errorf("consequence is not 1 statements. got=%d\n", len(expression.Consequence.Statements))
+	errorTests := []struct {
+		input  string
+		expect string
+	}{
+		{"match x { _ => 1 }", "1:7: expected nexttoken to be (, got IDENT instead"},
+		{"match (x) { + => 1 }", "1:13: expected pattern, got +"},
+		{"match (x) { 1 2 }", "1:15: expected nexttoken to be =>, got INT instead"},
+		{"match (x) { 1 => 2 3 => 4 }", "1:20: expected nexttoken to be }, got INT instead"},
 	}
 
-	consequence, ok := expression.Consequence.Statements[0].(*ast.EsNode)
-	if !ok {
-		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", expression.Consequence.Statements[0])
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
 	}
+}
 
-	if !testIdentifier(t, consequence.Value, "x") {
-		return
+func TestArrowFunction(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"x => x * 2", "fn(x) (x * 2)"},
+		{"(x, y) => x + y", "fn(x, y) (x + y)"},
+		{"() => 1", "fn() 1"},
+		{"(a, b = (1 + 2), ...r) => a", "fn(a, b = (1 + 2), ...r) a"},
+		{"x => y => x + y", "fn(x) fn(y) (x + y)"},
+		{"map(xs, x => x + 1)", "map(xs, fn(x) (x + 1))"},
+		{"xs |> filter((x) => x > 0)", "filter(xs, fn(x) (x > 0))"},
+		{"(x) + 1", "(x + 1)"},
+		{"(f(x)) * (y)", "(f(x) * y)"},
+		{"match (v) { x if x > 0 => n => n, _ => (n) => -n }", "match(v) { x if (x > 0) => fn(n) n, _ => fn(n) (-n) }"},
+		{"match (v) { x if (x) => 1 }", "match(v) { x if x => 1 }"},
+		{"match (v) { x if f((y) => y) => 1 }", "match(v) { x if f(fn(y) y) => 1 }"},
 	}
 
-	if len(expression.Alternative.Statements) != 1 {
-		t.Errorf("alternative is not 1 statements. got=%d\n", len(expression.Alternative.Statements))
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expect {
+			t.Errorf("expected=%q, got=%q", tt.expect, program.String())
+		}
 	}
 
-	alternative, ok := expression.Alternative.Statements[0].(*ast.EsNode)
-	if !ok {
-		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", expression.Alternative.Statements[0])
+	fn := parseFunctionForTest(t, "(a, b) => a")
+	if len(fn.Parameters) != 2 || fn.Body == nil || len(fn.Body.Statements) != 1 {
+		t.Errorf("arrow function not parsed into FunctionNode. got=%+v", fn)
 	}
 
-	if !testIdentifier(t, alternative.Value, "y") {
-		return
+	errorTests := []struct {
+		input  string
+		expect string
+	}{
+		{"(1) => 1", "1:2: expected nexttoken to be IDENT, got INT instead"},
+		{"(a, b => 1", "1:3: expected nexttoken to be ), got , instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
 	}
 }
 
@@ -556,6 +1171,49 @@ func TestFunctionParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Value, "x", "+", "y")
 }
 
+func TestFuncDeclParsing(t *testing.T) {
+	input := `fn add(x, y) { x + y; } fn(x) { x }(1)`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 2, len(program.Statements))
+	}
+
+	decl, ok := program.Statements[0].(*ast.FuncDeclNode)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FuncDeclNode. got=%T", program.Statements[0])
+	}
+
+	if decl.Name.Value != "add" {
+		t.Errorf("decl.Name wrong. got=%q", decl.Name.Value)
+	}
+
+	if len(decl.Function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d\n", len(decl.Function.Parameters))
+	}
+
+	testContentExpression(t, decl.Function.Parameters[0], "x")
+	testContentExpression(t, decl.Function.Parameters[1], "y")
+
+	if decl.String() != "fn add(x, y) (x + y)" {
+		t.Errorf("decl.String() wrong. got=%q", decl.String())
+	}
+
+	// 名前がなければ今まで通り関数式
+	stmt, ok := program.Statements[1].(*ast.EsNode)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.EsNode. got=%T", program.Statements[1])
+	}
+
+	if _, ok := stmt.Value.(*ast.CallNode); !ok {
+		t.Errorf("stmt.Value is not ast.CallNode. got=%T", stmt.Value)
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -586,6 +1244,84 @@ func TestFunctionParameterParsing(t *testing.T) {
 
 }
 
+func TestFunctionDefaultsAndRest(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"fn(a, b = 2) { a + b }", "fn(a, b = 2) (a + b)"},
+		{"fn(a = 1, b = a * 2) { b }", "fn(a = 1, b = (a * 2)) b"},
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest) rest"},
+		{"fn(...args) { args }", "fn(...args) args"},
+		{"fn greet(name = \"you\", ...more) { name }", "fn greet(name = you, ...more) name"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expect {
+			t.Errorf("expected=%q, got=%q", tt.expect, program.String())
+		}
+	}
+
+	fn := parseFunctionForTest(t, "fn(a, b = 2, ...c) {}")
+	if len(fn.Parameters) != 2 || len(fn.Defaults) != 2 {
+		t.Fatalf("wrong parameters. got=%d params, %d defaults", len(fn.Parameters), len(fn.Defaults))
+	}
+	if fn.Defaults[0] != nil {
+		t.Errorf("a should have no default. got=%s", fn.Defaults[0])
+	}
+	testIntegerContent(t, fn.Defaults[1], 2)
+	if fn.Rest == nil || fn.Rest.Value != "c" {
+		t.Errorf("wrong rest parameter. got=%+v", fn.Rest)
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"fn(a = 1, b) {}", "1:11: parameter b without default follows parameter with default"},
+		{"fn(...a, b) {}", "1:8: expected nexttoken to be ), got , instead"},
+		{"fn(1) {}", "1:4: expected nexttoken to be IDENT, got INT instead"},
+		{"fn(a..b) {}", "1:5: expected nexttoken to be ), got . instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
+	}
+}
+
+func parseFunctionForTest(t *testing.T, input string) *ast.FunctionNode {
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.EsNode)
+	fn, ok := stmt.Value.(*ast.FunctionNode)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionNode. got=%T", stmt.Value)
+	}
+	return fn
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -688,6 +1424,70 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	input := `"hello ${name}, you are ${age + 1}";`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.EsNode)
+	node, ok := stmt.Value.(*ast.InterpNode)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpNode. got=%T", stmt.Value)
+	}
+
+	if len(node.Strings) != 3 || node.Strings[0] != "hello " || node.Strings[1] != ", you are " || node.Strings[2] != "" {
+		t.Errorf("node.Strings wrong. got=%q", node.Strings)
+	}
+
+	if len(node.Exprs) != 2 {
+		t.Fatalf("node.Exprs does not contain 2 expressions. got=%d", len(node.Exprs))
+	}
+
+	testIdentifier(t, node.Exprs[0], "name")
+	testInfixExpression(t, node.Exprs[1], "age", "+", 1)
+
+	if node.String() != `"hello ${name}, you are ${(age + 1)}"` {
+		t.Errorf("node.String() wrong. got=%q", node.String())
+	}
+
+	errorTests := []struct {
+		input  string
+		expect string
+	}{
+		{`"a ${} b"`, "1:6: empty expression in string interpolation"},
+		{`"a ${x y} b"`, "1:8: expected } to close string interpolation, got IDENT"},
+		{`"a ${x`, "1:1: unterminated string literal"},
+	}
+
+	// 閉じていない${の後ろで、途中までの式が残らない
+	l = lexer.NewLexer(`! "${ fn`)
+	p = NewParser(l)
+	program = p.ParseProgram()
+
+	if program.String() != "<error>" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("expected 1 parser error for %q. got=%v", tt.input, errors)
+			continue
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
+	}
+}
+
 func TestParsingArray(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -1052,3 +1852,230 @@ func testBooleanContent(t *testing.T, expression ast.Expression, expectValue boo
 
 	return true
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"let x 5;", "script.mk:1:7: expected nexttoken to be =, got INT instead"},
+		{"let x = 5;\nlet = 10;", "script.mk:2:5: expected nexttoken to be IDENT, got = instead"},
+		{"1 +\n  )", "script.mk:2:3: no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, lexer.WithFile("script.mk"))
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+
+		if errors[0] != tt.expect {
+			t.Errorf("wrong error. expect=%q, got=%q", tt.expect, errors[0])
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect Diagnostic
+	}{
+		{"let x 5;", Diagnostic{Expected: "=", Got: "INT", Msg: "expected nexttoken to be =, got INT instead"}},
+		{"let x = 5", Diagnostic{Expected: ";", Got: "EOF", Msg: "missing \";\" after let statement, got EOF"}},
+		{"return 1 2", Diagnostic{Expected: ";", Got: "INT", Msg: "missing \";\" after return statement, got INT"}},
+		{"let x = ;", Diagnostic{Expected: "expression", Got: ";", Msg: "no prefix parse function for ; found"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) != 1 {
+			t.Errorf("%q - expected 1 diagnostic. got=%q", tt.input, p.Errors())
+			continue
+		}
+
+		d := diags[0]
+		if d.Expected != tt.expect.Expected || d.Got != tt.expect.Got || d.Msg != tt.expect.Msg {
+			t.Errorf("%q - wrong diagnostic. expect=%+v, got=%+v", tt.input, tt.expect, d)
+		}
+
+		if p.Errors()[0] != d.Pos.String()+": "+d.Msg {
+			t.Errorf("%q - Errors() not formatted from Diagnostics(). got=%q", tt.input, p.Errors()[0])
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectAST    string
+		expectErrors []string
+	}{
+		{
+			"let x 5; let y = 2; let = 3; y",
+			"<error>let y = 2;<error>y",
+			[]string{
+				"1:7: expected nexttoken to be =, got INT instead",
+				"1:25: expected nexttoken to be IDENT, got = instead",
+			},
+		},
+		{
+			"1 + ; 2 * ) ; let z = 4;",
+			"<error><error>let z = 4;",
+			[]string{
+				"1:5: no prefix parse function for ; found",
+				"1:11: no prefix parse function for ) found",
+			},
+		},
+		{
+			// ブロックの中で直して、ブロックの外は続きから
+			"let f = fn(a) { let b 1; return a; }; f(1)",
+			"let f = fn(a) <error>return a;;f(1)",
+			[]string{"1:23: expected nexttoken to be =, got INT instead"},
+		},
+		{
+			// 1つの文の中のエラーは最初の1つだけ
+			"let x = (1 + (2 * ; let y = 3;",
+			"<error>let y = 3;",
+			[]string{"1:19: no prefix parse function for ; found"},
+		},
+		{
+			// 字句解析のエラーだけ（続く+のエラーは出さない）
+			"@ + 1; let a = 1;",
+			"<error>let a = 1;",
+			[]string{"1:1: unexpected character '@' (U+0040)"},
+		},
+		{
+			// 途中までの f() や [] を残さない
+			"f(1 @); let a = 1;",
+			"<error>let a = 1;",
+			[]string{"1:5: unexpected character '@' (U+0040)"},
+		},
+		{
+			"[1, 2 @]; let a = 1;",
+			"<error>let a = 1;",
+			[]string{"1:7: unexpected character '@' (U+0040)"},
+		},
+		{
+			"for (x [1]) { x } while (true) { 1 }",
+			"<error>whiletrue 1",
+			[]string{"1:8: expected nexttoken to be IN, got [ instead"},
+		},
+		{
+			// 途中の{}の中の";"やletでは止まらない
+			"let x = if (y { let a = 1; 2 }; let z = 1;",
+			"<error>let z = 1;",
+			[]string{"1:15: expected nexttoken to be ), got { instead"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if program.String() != tt.expectAST {
+			t.Errorf("%q - wrong AST. expect=%q, got=%q", tt.input, tt.expectAST, program.String())
+		}
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectErrors) {
+			t.Errorf("%q - wrong number of errors. expect=%q, got=%q", tt.input, tt.expectErrors, errors)
+			continue
+		}
+
+		for i, msg := range tt.expectErrors {
+			if errors[i] != msg {
+				t.Errorf("%q - wrong error[%d]. expect=%q, got=%q", tt.input, i, msg, errors[i])
+			}
+		}
+	}
+}
+
+func TestTooManyErrors(t *testing.T) {
+	input := strings.Repeat("let = 1;\n", maxErrors+5)
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != maxErrors+1 {
+		t.Fatalf("expected %d errors. got=%d (%q)", maxErrors+1, len(errors), errors)
+	}
+
+	last := errors[len(errors)-1]
+	if last != fmt.Sprintf("%d:5: too many errors", maxErrors+1) {
+		t.Errorf("wrong last error. got=%q", last)
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect []string
+	}{
+		{"let x = 5 # 3;", []string{"1:11: unexpected character '#' (U+0023)"}},
+		{"let x = #;", []string{"1:9: unexpected character '#' (U+0023)"}},
+		{"puts(\"hi);", []string{"1:6: unterminated string literal"}},
+		{"foo(0b12)", []string{"1:5: invalid digit '2' in binary literal"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) < len(tt.expect) {
+			t.Errorf("%q - expected errors %q. got=%q", tt.input, tt.expect, errors)
+			continue
+		}
+
+		// 字句解析のエラーが先に出る
+		for i, msg := range tt.expect {
+			if errors[i] != msg {
+				t.Errorf("%q - wrong error[%d]. expect=%q, got=%q", tt.input, i, msg, errors[i])
+			}
+		}
+
+		for _, msg := range errors {
+			if strings.Contains(msg, "ILLEGAL") {
+				t.Errorf("%q - parser complained about ILLEGAL: %q", tt.input, msg)
+			}
+		}
+	}
+}
+
+func TestEmptyProgram(t *testing.T) {
+	for _, input := range []string{"", "   ", "\n"} {
+		l := lexer.NewLexer(input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 0 {
+			t.Errorf("program.Statements not empty for %q. got=%d", input, len(program.Statements))
+		}
+	}
+}
+
+func TestSkipComments(t *testing.T) {
+	input := `let x = /* five */ 5; // end`
+
+	l := lexer.NewLexer(input, lexer.WithComments())
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "let x = 5;" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/yuya-isaka/go-yuya-monkey/evaluator"
 	"github.com/yuya-isaka/go-yuya-monkey/lexer"
 	"github.com/yuya-isaka/go-yuya-monkey/object"
 	"github.com/yuya-isaka/go-yuya-monkey/parser"
+	"github.com/yuya-isaka/go-yuya-monkey/token"
 )
 
 const PROMPT = ">> "
@@ -34,8 +38,9 @@ func Start(in io.Reader, out io.Writer) {
 		p := parser.NewParser(l)
 		program := p.ParseProgram()
 
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+		// 字句解析のエラーもp.Diagnostics()に入っている
+		if diags := p.Diagnostics(); len(diags) != 0 {
+			printParseErrors(out, line, diags)
 			continue
 		}
 
@@ -47,7 +52,7 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
+func printParseErrors(out io.Writer, source string, diags []parser.Diagnostic) {
 	rand.NewSource(time.Now().UnixNano())
 	destinyNum := rand.Intn(2)
 
@@ -55,12 +60,74 @@ func printParseErrors(out io.Writer, errors []string) {
 	io.WriteString(out, wifes[destinyNum].message)
 	io.WriteString(out, "\n\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, d := range diags {
+		io.WriteString(out, "\t"+d.Error()+"\n")
+		io.WriteString(out, showSource(source, d.Pos))
 	}
 	io.WriteString(out, "\n")
 }
 
+// 端末で2マス使う文字（East Asian WidthがWかF）のおおまかな範囲
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115F, Stride: 1}, // ハングルの字母
+		{Lo: 0x2E80, Hi: 0x303E, Stride: 1}, // CJKの部首、記号、句読点
+		{Lo: 0x3041, Hi: 0x33FF, Stride: 1}, // ひらがな、カタカナ、CJKの互換文字
+		{Lo: 0x3400, Hi: 0x4DBF, Stride: 1}, // CJK統合漢字拡張A
+		{Lo: 0x4E00, Hi: 0x9FFF, Stride: 1}, // CJK統合漢字
+		{Lo: 0xA000, Hi: 0xA4CF, Stride: 1}, // イ文字
+		{Lo: 0xAC00, Hi: 0xD7A3, Stride: 1}, // ハングル
+		{Lo: 0xF900, Hi: 0xFAFF, Stride: 1}, // CJK互換漢字
+		{Lo: 0xFE30, Hi: 0xFE4F, Stride: 1}, // CJK互換形
+		{Lo: 0xFF00, Hi: 0xFF60, Stride: 1}, // 全角英数・記号
+		{Lo: 0xFFE0, Hi: 0xFFE6, Stride: 1}, // 全角記号
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1F300, Hi: 0x1F64F, Stride: 1}, // 絵文字
+		{Lo: 0x1F900, Hi: 0x1F9FF, Stride: 1}, // 絵文字
+		{Lo: 0x20000, Hi: 0x3FFFD, Stride: 1}, // CJK統合漢字拡張B以降
+	},
+}
+
+// エラーの行と、列の位置に"^"を出す
+//
+//	let x 5;
+//	      ^
+func showSource(source string, pos token.Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+
+	lines := strings.Split(source, "\n")
+	if pos.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+
+	// "^"の前はタブはタブのまま、それ以外は空白にする（列がずれないように）
+	// 全角の文字は端末で2マス使うので空白も2つ
+	var caret strings.Builder
+	for i, ch := range []rune(line) {
+		if i >= pos.Column-1 {
+			break
+		}
+		switch {
+		case ch == '\t':
+			caret.WriteRune('\t')
+		case unicode.Is(wide, ch):
+			caret.WriteString("  ")
+		default:
+			caret.WriteRune(' ')
+		}
+	}
+	// 行末（EOFや改行）を指しているときは行の長さより先
+	for i := utf8.RuneCountInString(line); i < pos.Column-1; i++ {
+		caret.WriteRune(' ')
+	}
+
+	return "\t    " + line + "\n" + "\t    " + caret.String() + "^\n"
+}
+
 var wifes = []struct {
 	ascii   string
 	message string
@@ -0,0 +1,30 @@
+package repl
+
+import (
+	"testing"
+
+	"github.com/yuya-isaka/go-yuya-monkey/token"
+)
+
+func TestShowSource(t *testing.T) {
+	tests := []struct {
+		source string
+		pos    token.Position
+		expect string
+	}{
+		{"let x 5;", token.Position{Line: 1, Column: 7}, "\t    let x 5;\n\t          ^\n"},
+		{"1 +", token.Position{Line: 1, Column: 4}, "\t    1 +\n\t       ^\n"},
+		{"let a = 1;\n\tlet = 2;", token.Position{Line: 2, Column: 6}, "\t    \tlet = 2;\n\t    \t    ^\n"},
+		{"\"ねこ\" + #", token.Position{Line: 1, Column: 8}, "\t    \"ねこ\" + #\n\t             ^\n"},
+		{"ｘ１ + 😀 @", token.Position{Line: 1, Column: 8}, "\t    ｘ１ + 😀 @\n\t              ^\n"},
+		{"café @", token.Position{Line: 1, Column: 6}, "\t    café @\n\t         ^\n"},
+		{"x", token.Position{}, ""},
+	}
+
+	for _, tt := range tests {
+		got := showSource(tt.source, tt.pos)
+		if got != tt.expect {
+			t.Errorf("showSource(%q, %v) wrong.\nexpect=%q\ngot=%q", tt.source, tt.pos, tt.expect, got)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package token
 
+import "fmt"
+
 const (
 	ILLEGAL   = "ILLEGAL"
 	EOF       = "EOF"
 	IDENT     = "IDENT"
 	INT       = "INT"
+	FLOAT     = "FLOAT"
 	ASSIGN    = "="
+	PLUS_EQ   = "+="
+	MINUS_EQ  = "-="
+	MUL_EQ    = "*="
+	DIV_EQ    = "/="
 	PLUS      = "+"
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -21,17 +28,46 @@ const (
 	GT        = ">"
 	EQ        = "=="
 	NOT_EQ    = "!="
+	LT_EQ     = "<="
+	GT_EQ     = ">="
+	PERCENT   = "%"
+	POWER     = "**"
+	AMPERSAND = "&"
+	PIPE      = "|"
+	PIPELINE  = "|>"
+	AND       = "&&"
+	OR        = "||"
+	CARET     = "^"
+	TILDE     = "~"
+	LSHIFT    = "<<"
+	RSHIFT    = ">>"
 	FUNCTION  = "FUNCTION"
 	LET       = "LET"
 	IF        = "IF"
 	ELSE      = "ELSE"
 	RETURN    = "RETURN"
+	WHILE     = "WHILE"
+	FOR       = "FOR"
+	IN        = "IN"
+	BREAK     = "BREAK"
+	CONTINUE  = "CONTINUE"
+	MATCH     = "MATCH"
 	TRUE      = "TRUE"
 	FALSE     = "FALSE"
 	STRING    = "STRING"
 	LBRACKET  = "["
 	RBRACKET  = "]"
 	COLON     = ":"
+	QUESTION  = "?"
+	ELLIPSIS  = "..."
+	DOT       = "."
+	ARROW     = "=>"
+	COMMENT   = "COMMENT"
+
+	// "a ${x} b ${y} c" の文字列の部分
+	INTERP_START = "INTERP_START" // "a ${
+	INTERP_MID   = "INTERP_MID"   // } b ${
+	INTERP_END   = "INTERP_END"   // } c"
 )
 
 type TokenType string
@@ -39,18 +75,54 @@ type TokenType string
 type Token struct {
 	Type TokenType // 型
 	Name string    // 名前
+	Pos  Position  // 位置（トークンの先頭）
+}
+
+// ソースコード上の位置
+type Position struct {
+	File   string // ファイル名（なければ空）
+	Line   int    // 行（1始まり）
+	Column int    // 列（1始まり）
+	Offset int    // 先頭からのバイトオフセット（0始まり）
+}
+
+// 行が0なら位置情報なし
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// file:line:column の形（ファイル名がなければ line:column）
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.File != "" {
+			return p.File
+		}
+		return "-"
+	}
+
+	s := fmt.Sprintf("%d:%d", p.Line, p.Column)
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
 }
 
 // キーワードたち
 // 名前で型を返す(名前→型)
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"true":     TRUE,
+	"false":    FALSE,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"match":    MATCH,
 }
 
 func LookKeyword(name string) TokenType {
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yuya-isaka/go-yuya-monkey/lexer"
+	"github.com/yuya-isaka/go-yuya-monkey/token"
+)
+
+// --jsonで出す1トークン分
+type tokenJSON struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	File    string          `json:"file,omitempty"`
+	Line    int             `json:"line"`
+	Column  int             `json:"column"`
+	Offset  int             `json:"offset"`
+}
+
+// monkey tokens [--json] [--comments] [file]
+// ファイル（なければ標準入力）のトークン列を1行1トークンで出す
+// 字句解析のエラーがあったら標準エラーに出して1を返す
+func runTokens(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print tokens as a JSON array")
+	comments := flags.Bool("comments", false, "include COMMENT tokens")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: monkey tokens [--json] [--comments] [file]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	opts := []lexer.Option{}
+	if *comments {
+		opts = append(opts, lexer.WithComments())
+	}
+
+	// "-"か省略なら標準入力
+	src := stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+
+		src = f
+		opts = append(opts, lexer.WithFile(path))
+	}
+
+	l := lexer.NewReaderLexer(src, opts...)
+
+	if *asJSON {
+		io.WriteString(stdout, "[\n")
+	}
+
+	// 大きなファイルでも溜め込まずに1つずつ出す
+	for first := true; ; first = false {
+		tok := l.NextToken()
+
+		// EOFの中身は"\x00"なので、見やすいように空にしておく
+		literal := tok.Name
+		if tok.Type == token.EOF {
+			literal = ""
+		}
+
+		if *asJSON {
+			b, _ := json.Marshal(tokenJSON{
+				Type:    tok.Type,
+				Literal: literal,
+				File:    tok.Pos.File,
+				Line:    tok.Pos.Line,
+				Column:  tok.Pos.Column,
+				Offset:  tok.Pos.Offset,
+			})
+			if !first {
+				io.WriteString(stdout, ",\n")
+			}
+			io.WriteString(stdout, "  ")
+			stdout.Write(b)
+		} else {
+			fmt.Fprintf(stdout, "%s\t%s\t%q\n", tok.Pos, tok.Type, literal)
+		}
+
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	if *asJSON {
+		io.WriteString(stdout, "\n]\n")
+	}
+
+	if errors := l.Errors(); len(errors) != 0 {
+		for _, msg := range errors {
+			fmt.Fprintln(stderr, msg)
+		}
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTokens(t *testing.T) {
+	input := "let x = 1; // c\nx"
+
+	tests := []struct {
+		args   []string
+		expect string
+	}{
+		{
+			[]string{},
+			`1:1	LET	"let"
+1:5	IDENT	"x"
+1:7	=	"="
+1:9	INT	"1"
+1:10	;	";"
+2:1	IDENT	"x"
+2:2	EOF	""
+`,
+		},
+		{
+			[]string{"--comments"},
+			`1:1	LET	"let"
+1:5	IDENT	"x"
+1:7	=	"="
+1:9	INT	"1"
+1:10	;	";"
+1:12	COMMENT	"// c"
+2:1	IDENT	"x"
+2:2	EOF	""
+`,
+		},
+		{
+			[]string{"--json", "-"},
+			`[
+  {"type":"LET","literal":"let","line":1,"column":1,"offset":0},
+  {"type":"IDENT","literal":"x","line":1,"column":5,"offset":4},
+  {"type":"=","literal":"=","line":1,"column":7,"offset":6},
+  {"type":"INT","literal":"1","line":1,"column":9,"offset":8},
+  {"type":";","literal":";","line":1,"column":10,"offset":9},
+  {"type":"IDENT","literal":"x","line":2,"column":1,"offset":16},
+  {"type":"EOF","literal":"","line":2,"column":2,"offset":17}
+]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runTokens(tt.args, strings.NewReader(input), &stdout, &stderr)
+
+		if code != 0 {
+			t.Errorf("args %v - exit code %d. stderr=%q", tt.args, code, stderr.String())
+		}
+
+		if stdout.String() != tt.expect {
+			t.Errorf("args %v - wrong output.\nexpect=%q\ngot=%q", tt.args, tt.expect, stdout.String())
+		}
+	}
+}
+
+func TestRunTokensFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(path, []byte(`"oops`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runTokens([]string{path}, strings.NewReader(""), &stdout, &stderr)
+
+	if code != 1 {
+		t.Errorf("expected exit code 1. got=%d", code)
+	}
+
+	expect := path + ":1:1\tILLEGAL\t\"oops\"\n" + path + ":1:6\tEOF\t\"\"\n"
+	if stdout.String() != expect {
+		t.Errorf("wrong output.\nexpect=%q\ngot=%q", expect, stdout.String())
+	}
+
+	if stderr.String() != path+":1:1: unterminated string literal\n" {
+		t.Errorf("wrong stderr. got=%q", stderr.String())
+	}
+}